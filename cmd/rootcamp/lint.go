@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bobparsons/rootcamp/internal/lessons"
+)
+
+const lintCommand = "lint"
+
+// runLint checks the built-in lessons and every pack, printing what it finds.
+// It exits non-zero if anything would stop a lesson or pack from loading, so
+// pack authors can run it before publishing.
+func runLint(args []string) {
+	flags := flag.NewFlagSet(lintCommand, flag.ExitOnError)
+	var packs packFlag
+	flags.Var(&packs, "pack", "directory to lint as a lesson pack, on top of those in ~/.rootcamp/packs (may be repeated)")
+	schema := flags.String("schema", "", "print the JSON Schema for "+strings.Join(lessons.SchemaNames, ", ")+" files and exit")
+	flags.Parse(args)
+
+	if *schema != "" {
+		content, err := lessons.Schema(*schema)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Stdout.Write(content)
+		os.Exit(0)
+	}
+
+	for _, dir := range packs {
+		lessons.AddPackDir(dir)
+	}
+
+	errors, warnings := 0, 0
+	for _, issue := range lessons.Lint() {
+		fmt.Println(issue)
+		if issue.Warning {
+			warnings++
+		} else {
+			errors++
+		}
+	}
+	fmt.Printf("%d error(s), %d warning(s)\n", errors, warnings)
+	if errors > 0 {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
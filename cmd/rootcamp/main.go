@@ -1,15 +1,51 @@
 package main
 
 import (
+	"database/sql"
+	"flag"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/bobparsons/rootcamp/internal/db"
+	"github.com/bobparsons/rootcamp/internal/lab"
+	"github.com/bobparsons/rootcamp/internal/lessons"
 	"github.com/bobparsons/rootcamp/internal/tui"
 	tea "github.com/charmbracelet/bubbletea"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == lab.InitCommand {
+		lab.RunInit(os.Args[2:])
+	}
+	if len(os.Args) > 1 && os.Args[1] == lintCommand {
+		runLint(os.Args[2:])
+	}
+	if len(os.Args) > 1 && os.Args[1] == selftestCommand {
+		runSelftest(os.Args[2:])
+	}
+
+	sandboxRoot := flag.String("sandbox-root", "", "directory to create lab sandboxes in (overrides $"+lab.RootEnv+" and the saved setting)")
+	var packs packFlag
+	flag.Var(&packs, "pack", "directory to load as a lesson pack, on top of those in ~/.rootcamp/packs (may be repeated)")
+	flag.Parse()
+
+	for _, dir := range packs {
+		info, err := os.Stat(dir)
+		if err != nil || !info.IsDir() {
+			fmt.Printf("Lesson pack %s is not a directory\n", dir)
+			os.Exit(1)
+		}
+		lessons.AddPackDir(dir)
+	}
+	if _, err := lessons.LoadLessons(); err != nil {
+		fmt.Printf("Failed to load lessons: %v\n", err)
+		os.Exit(1)
+	}
+	for _, err := range lessons.PackErrors() {
+		fmt.Fprintf(os.Stderr, "Skipping %v\n", err)
+	}
+
 	database, err := db.InitDB()
 	if err != nil {
 		fmt.Printf("Failed to initialize database: %v\n", err)
@@ -17,11 +53,57 @@ func main() {
 	}
 	defer database.Close()
 
+	if err := configureSandboxRoot(database, *sandboxRoot); err != nil {
+		fmt.Printf("Invalid sandbox root: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Sweep up sandboxes left behind by a run that crashed or was killed.
+	if _, leftover, err := lab.Reap(); err == nil {
+		for _, path := range leftover {
+			fmt.Fprintf(os.Stderr, "Found %s, which may be a sandbox from an older version of RootCamp; remove it if so\n", path)
+		}
+	}
+
 	model := tui.NewWelcomeModel(database)
 	p := tea.NewProgram(&model, tea.WithAltScreen())
 
-	if _, err := p.Run(); err != nil {
+	_, err = p.Run()
+	lab.CleanupAll()
+	if err != nil {
 		fmt.Printf("Error running program: %v\n", err)
 		os.Exit(1)
 	}
 }
+
+// configureSandboxRoot picks the sandbox root from the command line flag,
+// then the environment, then the saved setting, falling back to the default.
+func configureSandboxRoot(database *sql.DB, fromFlag string) error {
+	root := fromFlag
+	if root == "" {
+		root = os.Getenv(lab.RootEnv)
+	}
+	if root == "" {
+		settings, err := db.GetAllSettings(database)
+		if err != nil {
+			return fmt.Errorf("failed to load settings: %w", err)
+		}
+		root = settings.SandboxRoot
+	}
+	if root == "" {
+		return nil
+	}
+	return lab.SetRoot(root)
+}
+
+// packFlag collects every --pack given on the command line.
+type packFlag []string
+
+func (p *packFlag) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *packFlag) Set(dir string) error {
+	*p = append(*p, dir)
+	return nil
+}
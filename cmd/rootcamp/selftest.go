@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bobparsons/rootcamp/internal/lab"
+	"github.com/bobparsons/rootcamp/internal/lessons"
+)
+
+const selftestCommand = "selftest"
+
+// runSelftest runs every lesson's reference solution in a fresh sandbox and
+// reports the lessons that do not pass with it. Lesson IDs given as
+// arguments limit the run to those lessons.
+func runSelftest(args []string) {
+	flags := flag.NewFlagSet(selftestCommand, flag.ExitOnError)
+	var packs packFlag
+	flags.Var(&packs, "pack", "directory to load as a lesson pack, on top of those in ~/.rootcamp/packs (may be repeated)")
+	verbose := flags.Bool("v", false, "show each solution's answer and shell output when it fails")
+	flags.Parse(args)
+
+	if root := os.Getenv(lab.RootEnv); root != "" {
+		if err := lab.SetRoot(root); err != nil {
+			fmt.Printf("Invalid sandbox root: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	for _, dir := range packs {
+		lessons.AddPackDir(dir)
+	}
+	data, err := lessons.LoadLessons()
+	if err != nil {
+		fmt.Printf("Failed to load lessons: %v\n", err)
+		os.Exit(1)
+	}
+	for _, err := range lessons.PackErrors() {
+		fmt.Fprintf(os.Stderr, "Skipping %v\n", err)
+	}
+
+	only := map[string]bool{}
+	missing := map[string]bool{}
+	for _, id := range flags.Args() {
+		only[id] = true
+		missing[id] = true
+	}
+
+	passed, failed, skipped := 0, 0, 0
+	for _, lesson := range data.Lessons {
+		if len(only) > 0 && !only[lesson.ID] {
+			continue
+		}
+		delete(missing, lesson.ID)
+
+		result, err := lab.RunSolution(lesson)
+		switch {
+		case errors.Is(err, lab.ErrNoSolution):
+			skipped++
+			if *verbose {
+				fmt.Printf("skip %s\n", lesson.ID)
+			}
+		case err != nil:
+			failed++
+			fmt.Printf("FAIL %s: %v\n", lesson.ID, err)
+		case len(result.Failures) > 0:
+			failed++
+			fmt.Printf("FAIL %s: %s\n", lesson.ID, strings.Join(result.Failures, "; "))
+			if *verbose {
+				fmt.Printf("     answer: %q\n", result.Answer)
+				if result.Stderr != "" {
+					fmt.Printf("     %s\n", strings.ReplaceAll(result.Stderr, "\n", "\n     "))
+				}
+			}
+		default:
+			passed++
+			fmt.Printf("ok   %s\n", lesson.ID)
+		}
+	}
+	for id := range missing {
+		failed++
+		fmt.Printf("FAIL %s: no such lesson\n", id)
+	}
+
+	fmt.Printf("%d passed, %d failed, %d without a solution\n", passed, failed, skipped)
+	if failed > 0 {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
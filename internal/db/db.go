@@ -28,9 +28,9 @@ func InitDB() (*sql.DB, error) {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	if err := createSchema(db); err != nil {
+	if err := migrate(db, filepath.Join(dbDir, "backups")); err != nil {
 		db.Close()
-		return nil, fmt.Errorf("failed to create schema: %w", err)
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
 	}
 
 	if err := InitDefaultSettings(db); err != nil {
@@ -41,30 +41,11 @@ func InitDB() (*sql.DB, error) {
 	return db, nil
 }
 
-func createSchema(db *sql.DB) error {
-	schema := `
-	CREATE TABLE IF NOT EXISTS settings (
-		setting_name TEXT PRIMARY KEY,
-		setting_value TEXT NOT NULL,
-		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);
-
-	CREATE TABLE IF NOT EXISTS progress (
-		lesson_id TEXT PRIMARY KEY,
-		completed BOOLEAN DEFAULT FALSE,
-		completed_at DATETIME,
-		attempts INTEGER DEFAULT 0
-	);
-	`
-
-	_, err := db.Exec(schema)
-	return err
-}
-
 func InitDefaultSettings(db *sql.DB) error {
 	defaults := map[string]string{
 		"skip_intro_animation": "false",
 		"use_basic_bash":       "false",
+		"isolate_sandbox":      "false",
 	}
 
 	for name, value := range defaults {
@@ -122,9 +103,21 @@ func GetAllSettings(db *sql.DB) (*types.Settings, error) {
 		return nil, err
 	}
 
+	isolateSandbox, err := GetSetting(db, "isolate_sandbox", "false")
+	if err != nil {
+		return nil, err
+	}
+
+	sandboxRoot, err := GetSetting(db, "sandbox_root", "")
+	if err != nil {
+		return nil, err
+	}
+
 	return &types.Settings{
 		SkipIntroAnimation: stringToBool(skipIntro),
 		UseBasicBash:       stringToBool(useBasicBash),
+		IsolateSandbox:     stringToBool(isolateSandbox),
+		SandboxRoot:        sandboxRoot,
 	}, nil
 }
 
@@ -140,17 +133,19 @@ func BoolToString(b bool) string {
 }
 
 func GetProgress(db *sql.DB, lessonID string) (*types.UserProgress, error) {
-	query := `SELECT lesson_id, completed, completed_at, attempts
+	query := `SELECT lesson_id, completed, completed_at, attempts, tested_out, tested_out_at
 	          FROM progress WHERE lesson_id = ?`
 
 	var progress types.UserProgress
-	var completedAt sql.NullTime
+	var completedAt, testedOutAt sql.NullTime
 
 	err := db.QueryRow(query, lessonID).Scan(
 		&progress.LessonID,
 		&progress.Completed,
 		&completedAt,
 		&progress.Attempts,
+		&progress.TestedOut,
+		&testedOutAt,
 	)
 
 	if err == sql.ErrNoRows {
@@ -168,12 +163,15 @@ func GetProgress(db *sql.DB, lessonID string) (*types.UserProgress, error) {
 	if completedAt.Valid {
 		progress.CompletedAt = &completedAt.Time
 	}
+	if testedOutAt.Valid {
+		progress.TestedOutAt = &testedOutAt.Time
+	}
 
 	return &progress, nil
 }
 
 func GetAllProgress(db *sql.DB) (map[string]*types.UserProgress, error) {
-	query := `SELECT lesson_id, completed, completed_at, attempts FROM progress`
+	query := `SELECT lesson_id, completed, completed_at, attempts, tested_out, tested_out_at FROM progress`
 
 	rows, err := db.Query(query)
 	if err != nil {
@@ -185,13 +183,15 @@ func GetAllProgress(db *sql.DB) (map[string]*types.UserProgress, error) {
 
 	for rows.Next() {
 		var progress types.UserProgress
-		var completedAt sql.NullTime
+		var completedAt, testedOutAt sql.NullTime
 
 		err := rows.Scan(
 			&progress.LessonID,
 			&progress.Completed,
 			&completedAt,
 			&progress.Attempts,
+			&progress.TestedOut,
+			&testedOutAt,
 		)
 		if err != nil {
 			return nil, err
@@ -200,6 +200,9 @@ func GetAllProgress(db *sql.DB) (map[string]*types.UserProgress, error) {
 		if completedAt.Valid {
 			progress.CompletedAt = &completedAt.Time
 		}
+		if testedOutAt.Valid {
+			progress.TestedOutAt = &testedOutAt.Time
+		}
 
 		progressMap[progress.LessonID] = &progress
 	}
@@ -231,3 +234,245 @@ func MarkComplete(db *sql.DB, lessonID string) error {
 	_, err := db.Exec(query, lessonID)
 	return err
 }
+
+// MarkTestedOut records that the learner tested out of the lessons with the
+// placement test. Lessons already completed keep their completion, and the
+// time a lesson was first tested out of is kept.
+func MarkTestedOut(db *sql.DB, lessonIDs []string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, lessonID := range lessonIDs {
+		_, err := tx.Exec(`
+			INSERT INTO progress (lesson_id, tested_out, tested_out_at)
+			VALUES (?, TRUE, CURRENT_TIMESTAMP)
+			ON CONFLICT(lesson_id) DO UPDATE SET
+				tested_out = TRUE,
+				tested_out_at = COALESCE(tested_out_at, CURRENT_TIMESTAMP)
+		`, lessonID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// MarkStepComplete records a finished step of a multi-step lesson, keeping
+// the time it was first completed.
+func MarkStepComplete(db *sql.DB, lessonID, stepID string) error {
+	_, err := db.Exec(`INSERT OR IGNORE INTO step_progress (lesson_id, step_id) VALUES (?, ?)`, lessonID, stepID)
+	return err
+}
+
+// GetCompletedSteps returns the IDs of the lesson's steps that have ever
+// been completed.
+func GetCompletedSteps(db *sql.DB, lessonID string) (map[string]bool, error) {
+	rows, err := db.Query(`SELECT step_id FROM step_progress WHERE lesson_id = ?`, lessonID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	steps := make(map[string]bool)
+	for rows.Next() {
+		var stepID string
+		if err := rows.Scan(&stepID); err != nil {
+			return nil, err
+		}
+		steps[stepID] = true
+	}
+	return steps, rows.Err()
+}
+
+// OpenCourse records that the learner opened a course, starting it the
+// first time.
+func OpenCourse(db *sql.DB, courseID string) error {
+	query := `
+		INSERT INTO course_progress (course_id)
+		VALUES (?)
+		ON CONFLICT(course_id) DO UPDATE SET
+			last_opened_at = CURRENT_TIMESTAMP
+	`
+
+	_, err := db.Exec(query, courseID)
+	return err
+}
+
+// MarkCourseComplete records when the learner finished the last lesson of a
+// course, keeping the first time it happened.
+func MarkCourseComplete(db *sql.DB, courseID string) error {
+	query := `
+		INSERT INTO course_progress (course_id, completed_at)
+		VALUES (?, CURRENT_TIMESTAMP)
+		ON CONFLICT(course_id) DO UPDATE SET
+			completed_at = COALESCE(completed_at, CURRENT_TIMESTAMP)
+	`
+
+	_, err := db.Exec(query, courseID)
+	return err
+}
+
+func GetAllCourseProgress(db *sql.DB) (map[string]*types.CourseProgress, error) {
+	query := `SELECT course_id, started_at, last_opened_at, completed_at FROM course_progress`
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	progressMap := make(map[string]*types.CourseProgress)
+
+	for rows.Next() {
+		var progress types.CourseProgress
+		var startedAt, lastOpenedAt, completedAt sql.NullTime
+
+		if err := rows.Scan(&progress.CourseID, &startedAt, &lastOpenedAt, &completedAt); err != nil {
+			return nil, err
+		}
+
+		if startedAt.Valid {
+			progress.StartedAt = &startedAt.Time
+		}
+		if lastOpenedAt.Valid {
+			progress.LastOpenedAt = &lastOpenedAt.Time
+		}
+		if completedAt.Valid {
+			progress.CompletedAt = &completedAt.Time
+		}
+
+		progressMap[progress.CourseID] = &progress
+	}
+
+	return progressMap, rows.Err()
+}
+
+// StartLabAttempt records a new lab run along with the secret code generated
+// for it.
+func StartLabAttempt(db *sql.DB, lessonID, secret string) (int64, error) {
+	query := `INSERT INTO lab_attempts (lesson_id, secret) VALUES (?, ?)`
+
+	result, err := db.Exec(query, lessonID, secret)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// RecordLabReset counts a reset of the lab sandbox against the attempt.
+func RecordLabReset(db *sql.DB, attemptID int64) error {
+	_, err := db.Exec(`UPDATE lab_attempts SET resets = resets + 1 WHERE id = ?`, attemptID)
+	return err
+}
+
+func FinishLabAttempt(db *sql.DB, attemptID int64, passed bool, history []types.CommandRecord) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE lab_attempts SET finished_at = CURRENT_TIMESTAMP, passed = ?
+		WHERE id = ?
+	`, passed, attemptID)
+	if err != nil {
+		return err
+	}
+
+	for i, record := range history {
+		_, err := tx.Exec(`
+			INSERT OR REPLACE INTO command_history (attempt_id, seq, executed_at, exit_status, cwd, command)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, attemptID, i+1, record.ExecutedAt, record.ExitStatus, record.Dir, record.Command)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// RecordQuizAttempt saves a finished quiz along with the learner's answer to
+// each item.
+func RecordQuizAttempt(db *sql.DB, lessonID string, results []types.QuizResult, passed bool) error {
+	score := 0
+	for _, result := range results {
+		if result.Correct {
+			score++
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT INTO quiz_attempts (lesson_id, score, total, passed)
+		VALUES (?, ?, ?, ?)
+	`, lessonID, score, len(results), passed)
+	if err != nil {
+		return err
+	}
+	attemptID, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	for _, r := range results {
+		_, err := tx.Exec(`
+			INSERT INTO quiz_answers (attempt_id, item_id, answer, correct)
+			VALUES (?, ?, ?, ?)
+		`, attemptID, r.ItemID, r.Answer, r.Correct)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetAllReviewStates returns the schedule of every card reviewed so far,
+// keyed by card ID.
+func GetAllReviewStates(db *sql.DB) (map[string]*types.ReviewState, error) {
+	query := `SELECT card_id, lesson_id, ease, interval_days, repetitions, due_at, last_reviewed_at FROM review_cards`
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	states := make(map[string]*types.ReviewState)
+
+	for rows.Next() {
+		var state types.ReviewState
+		var lastReviewedAt sql.NullTime
+
+		if err := rows.Scan(&state.CardID, &state.LessonID, &state.Ease, &state.IntervalDays, &state.Repetitions, &state.DueAt, &lastReviewedAt); err != nil {
+			return nil, err
+		}
+		if lastReviewedAt.Valid {
+			state.LastReviewedAt = &lastReviewedAt.Time
+		}
+
+		states[state.CardID] = &state
+	}
+
+	return states, rows.Err()
+}
+
+// SaveReviewState stores a card's schedule after a review.
+func SaveReviewState(db *sql.DB, state types.ReviewState) error {
+	_, err := db.Exec(`
+		INSERT OR REPLACE INTO review_cards (card_id, lesson_id, ease, interval_days, repetitions, due_at, last_reviewed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, state.CardID, state.LessonID, state.Ease, state.IntervalDays, state.Repetitions, state.DueAt, state.LastReviewedAt)
+	return err
+}
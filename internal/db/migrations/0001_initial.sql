@@ -0,0 +1,79 @@
+-- The schema as it stood when migrations were introduced. Databases created
+-- before then already have some or all of these tables, which is why each is
+-- created only if it doesn't exist.
+
+CREATE TABLE IF NOT EXISTS settings (
+	setting_name TEXT PRIMARY KEY,
+	setting_value TEXT NOT NULL,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS progress (
+	lesson_id TEXT PRIMARY KEY,
+	completed BOOLEAN DEFAULT FALSE,
+	completed_at DATETIME,
+	attempts INTEGER DEFAULT 0,
+	tested_out BOOLEAN DEFAULT FALSE,
+	tested_out_at DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS lab_attempts (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	lesson_id TEXT NOT NULL,
+	started_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	finished_at DATETIME,
+	passed BOOLEAN DEFAULT FALSE,
+	resets INTEGER DEFAULT 0,
+	secret TEXT
+);
+
+CREATE TABLE IF NOT EXISTS command_history (
+	attempt_id INTEGER NOT NULL REFERENCES lab_attempts(id) ON DELETE CASCADE,
+	seq INTEGER NOT NULL,
+	executed_at DATETIME NOT NULL,
+	exit_status INTEGER NOT NULL,
+	cwd TEXT NOT NULL,
+	command TEXT NOT NULL,
+	PRIMARY KEY (attempt_id, seq)
+);
+
+CREATE TABLE IF NOT EXISTS step_progress (
+	lesson_id TEXT NOT NULL,
+	step_id TEXT NOT NULL,
+	completed_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (lesson_id, step_id)
+);
+
+CREATE TABLE IF NOT EXISTS course_progress (
+	course_id TEXT PRIMARY KEY,
+	started_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	last_opened_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	completed_at DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS quiz_attempts (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	lesson_id TEXT NOT NULL,
+	finished_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	score INTEGER NOT NULL,
+	total INTEGER NOT NULL,
+	passed BOOLEAN NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS quiz_answers (
+	attempt_id INTEGER NOT NULL REFERENCES quiz_attempts(id) ON DELETE CASCADE,
+	item_id TEXT NOT NULL,
+	answer TEXT NOT NULL,
+	correct BOOLEAN NOT NULL,
+	PRIMARY KEY (attempt_id, item_id)
+);
+
+CREATE TABLE IF NOT EXISTS review_cards (
+	card_id TEXT PRIMARY KEY,
+	lesson_id TEXT NOT NULL,
+	ease REAL NOT NULL,
+	interval_days INTEGER NOT NULL,
+	repetitions INTEGER NOT NULL,
+	due_at DATETIME NOT NULL,
+	last_reviewed_at DATETIME
+);
@@ -0,0 +1,305 @@
+package lab
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/bobparsons/rootcamp/internal/types"
+)
+
+// maxArchiveSize keeps a runaway archive from being read into memory when
+// checking a learner's work. Sandbox archives are a few kilobytes.
+const maxArchiveSize = 64 << 20
+
+type archiveEntry struct {
+	name  string
+	isDir bool
+	data  []byte
+}
+
+// readArchive lists the members of a tar, zip, gzip or bzip2 file, going by
+// its contents rather than its name. A compressed tarball is read as the tar
+// inside it; any other compressed file as a single member named after it.
+// Member names lose any leading "./" or "/".
+func readArchive(fullPath string) ([]archiveEntry, error) {
+	data, err := readLimited(fullPath)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")), bytes.HasPrefix(data, []byte("PK\x05\x06")):
+		return readZip(data)
+	case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		name := zr.Name
+		if name == "" {
+			name = strings.TrimSuffix(path.Base(fullPath), ".gz")
+		}
+		return readCompressed(zr, name)
+	case bytes.HasPrefix(data, []byte("BZh")):
+		name := strings.TrimSuffix(path.Base(fullPath), ".bz2")
+		return readCompressed(bzip2.NewReader(bytes.NewReader(data)), name)
+	case isTar(data):
+		return readTar(data)
+	}
+	return nil, fmt.Errorf("%s is not a recognised archive", path.Base(fullPath))
+}
+
+func readLimited(fullPath string) ([]byte, error) {
+	file, err := os.Open(fullPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return readAllLimited(file)
+}
+
+func readAllLimited(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxArchiveSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxArchiveSize {
+		return nil, fmt.Errorf("archive is too large to inspect")
+	}
+	return data, nil
+}
+
+func readCompressed(r io.Reader, name string) ([]archiveEntry, error) {
+	data, err := readAllLimited(r)
+	if err != nil {
+		return nil, err
+	}
+	if isTar(data) {
+		return readTar(data)
+	}
+	return []archiveEntry{{name: name, data: data}}, nil
+}
+
+func isTar(data []byte) bool {
+	return len(data) >= 262 && bytes.HasPrefix(data[257:], []byte("ustar"))
+}
+
+func readTar(data []byte) ([]archiveEntry, error) {
+	var entries []archiveEntry
+	tr := tar.NewReader(bytes.NewReader(data))
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		entry := archiveEntry{name: memberName(hdr.Name), isDir: hdr.Typeflag == tar.TypeDir}
+		if hdr.Typeflag == tar.TypeReg {
+			if entry.data, err = io.ReadAll(tr); err != nil {
+				return nil, err
+			}
+		}
+		if entry.name != "" {
+			entries = append(entries, entry)
+		}
+	}
+}
+
+func readZip(data []byte) ([]archiveEntry, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	var entries []archiveEntry
+	for _, file := range zr.File {
+		entry := archiveEntry{name: memberName(file.Name), isDir: file.FileInfo().IsDir()}
+		if !entry.isDir {
+			rc, err := file.Open()
+			if err != nil {
+				return nil, err
+			}
+			entry.data, err = readAllLimited(rc)
+			rc.Close()
+			if err != nil {
+				return nil, err
+			}
+		}
+		if entry.name != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+func memberName(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
+
+// archiveListing returns the archive's files, plus its directories with a
+// trailing slash. Directories are included whether or not the archive
+// stores an entry for them, since tools differ on that.
+func archiveListing(entries []archiveEntry) (files, dirs []string) {
+	seenDir := map[string]bool{}
+	addDir := func(dir string) {
+		if dir != "." && dir != "" && !seenDir[dir] {
+			seenDir[dir] = true
+			dirs = append(dirs, dir+"/")
+		}
+	}
+	for _, entry := range entries {
+		if entry.isDir {
+			addDir(entry.name)
+			continue
+		}
+		files = append(files, entry.name)
+		for dir := path.Dir(entry.name); dir != "."; dir = path.Dir(dir) {
+			addDir(dir)
+		}
+	}
+	slices.Sort(files)
+	slices.Sort(dirs)
+	return files, dirs
+}
+
+func sandboxArchive(relPath, sandboxPath string) ([]archiveEntry, bool) {
+	fullPath, ok := sandboxFile(sandboxPath, relPath)
+	if !ok {
+		return nil, false
+	}
+	entries, err := readArchive(fullPath)
+	return entries, err == nil
+}
+
+func normalizeEntries(entries []string) []string {
+	out := make([]string, len(entries))
+	for i, entry := range entries {
+		out[i] = memberName(entry)
+		if strings.HasSuffix(entry, "/") {
+			out[i] += "/"
+		}
+	}
+	return out
+}
+
+// archiveContainsScore checks that the archive at relPath holds every
+// listed member, scoring the share that are present. Directories are written
+// with a trailing slash.
+func archiveContainsScore(relPath string, entries []string, sandboxPath string) float64 {
+	archive, ok := sandboxArchive(relPath, sandboxPath)
+	if !ok {
+		return 0
+	}
+	if len(entries) == 0 {
+		return 1
+	}
+	files, dirs := archiveListing(archive)
+	found := 0
+	for _, entry := range normalizeEntries(entries) {
+		if slices.Contains(files, entry) || slices.Contains(dirs, entry) {
+			found++
+		}
+	}
+	return float64(found) / float64(len(entries))
+}
+
+// validateArchiveMatches checks that the archive holds exactly the listed
+// files. Listed directories must be present, but unlisted ones are ignored
+// because tar and zip add them depending on how they were invoked.
+func validateArchiveMatches(relPath string, entries []string, sandboxPath string) bool {
+	archive, ok := sandboxArchive(relPath, sandboxPath)
+	if !ok {
+		return false
+	}
+	files, dirs := archiveListing(archive)
+
+	var expected []string
+	for _, entry := range normalizeEntries(entries) {
+		if strings.HasSuffix(entry, "/") {
+			if !slices.Contains(dirs, entry) {
+				return false
+			}
+			continue
+		}
+		expected = append(expected, entry)
+	}
+	slices.Sort(expected)
+	return slices.Equal(files, expected)
+}
+
+// validateArchiveMemberContains checks that one file inside the archive
+// contains the expected text.
+func validateArchiveMemberContains(relPath, member, expected, sandboxPath string) bool {
+	archive, ok := sandboxArchive(relPath, sandboxPath)
+	if !ok {
+		return false
+	}
+	member = memberName(member)
+	for _, entry := range archive {
+		if !entry.isDir && entry.name == member {
+			return strings.Contains(string(entry.data), expected)
+		}
+	}
+	return false
+}
+
+// explainArchiveFailure tells the learner what their archive holds when it
+// does not match, which is hard to see from inside the lab shell.
+func explainArchiveFailure(req types.Requirement, sandboxPath string) (string, bool) {
+	fullPath, ok := sandboxFile(sandboxPath, req.Path)
+	if !ok {
+		return "", false
+	}
+	if _, err := os.Stat(fullPath); err != nil {
+		return "", false
+	}
+	archive, err := readArchive(fullPath)
+	if err != nil {
+		return fmt.Sprintf("%s (%s could not be read as an archive)", req.Description, req.Path), true
+	}
+
+	files, _ := archiveListing(archive)
+	if req.Validator == "archive_member_contains" {
+		if !slices.Contains(files, memberName(req.Member)) {
+			return fmt.Sprintf("%s (%s has no %s)", req.Description, req.Path, memberName(req.Member)), true
+		}
+		return "", false
+	}
+
+	var missing, extra []string
+	for _, entry := range normalizeEntries(req.Entries) {
+		if !strings.HasSuffix(entry, "/") && !slices.Contains(files, entry) {
+			missing = append(missing, entry)
+		}
+	}
+	if req.Validator == "archive_matches" {
+		wanted := normalizeEntries(req.Entries)
+		for _, file := range files {
+			if !slices.Contains(wanted, file) {
+				extra = append(extra, file)
+			}
+		}
+	}
+
+	var notes []string
+	if len(missing) > 0 {
+		notes = append(notes, "missing "+strings.Join(missing, ", "))
+	}
+	if len(extra) > 0 {
+		notes = append(notes, "unexpected "+strings.Join(extra, ", "))
+	}
+	if len(notes) == 0 {
+		return "", false
+	}
+	return fmt.Sprintf("%s (%s: %s)", req.Description, req.Path, strings.Join(notes, "; ")), true
+}
@@ -0,0 +1,167 @@
+package lab
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/bobparsons/rootcamp/internal/types"
+)
+
+// validateComputed checks the learner's answer against one worked out from
+// the lesson's data file. Counts may be pasted straight from wc or grep -c,
+// so only their first word has to match.
+func validateComputed(req types.Requirement, userInput, sandboxPath string) bool {
+	answers, err := ComputeAnswer(req, sandboxPath)
+	if err != nil {
+		return false
+	}
+	actual := strings.TrimSpace(userInput)
+	fields := strings.Fields(actual)
+	for _, answer := range answers {
+		if actual == answer {
+			return true
+		}
+		if _, err := strconv.Atoi(answer); err == nil && len(fields) > 0 && fields[0] == answer {
+			return true
+		}
+	}
+	return false
+}
+
+// ComputeAnswer works out every acceptable answer for a computed
+// requirement. The file is read from the sandbox snapshot, so it does not
+// matter if the learner has since sorted or edited it in place.
+func ComputeAnswer(req types.Requirement, sandboxPath string) ([]string, error) {
+	if req.Compute == nil {
+		return nil, fmt.Errorf("computed requirement has no compute rule")
+	}
+	rel := filepath.Clean("/" + req.Path)
+	content, err := os.ReadFile(filepath.Join(snapshotDir(sandboxPath), rel))
+	if err != nil {
+		content, err = os.ReadFile(filepath.Join(sandboxPath, rel))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", req.Path, err)
+		}
+	}
+
+	c := req.Compute
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	if len(content) == 0 {
+		lines = nil
+	}
+
+	switch c.Op {
+	case "line_count":
+		return []string{strconv.Itoa(len(lines))}, nil
+	case "word_count":
+		return []string{strconv.Itoa(len(strings.Fields(string(content))))}, nil
+	}
+
+	if c.Header && len(lines) > 0 {
+		lines = lines[1:]
+	}
+	values := lines
+	if c.Field > 0 {
+		delim := c.Delimiter
+		if delim == "" {
+			delim = "\t"
+		}
+		values = make([]string, len(lines))
+		for i, line := range lines {
+			values[i] = cutField(line, delim, c.Field)
+		}
+	}
+
+	switch c.Op {
+	case "match_count":
+		re, err := regexp.Compile(c.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern: %w", err)
+		}
+		count := 0
+		for _, value := range values {
+			if re.MatchString(value) {
+				count++
+			}
+		}
+		return []string{strconv.Itoa(count)}, nil
+
+	case "unique_count":
+		seen := map[string]bool{}
+		for _, value := range values {
+			seen[value] = true
+		}
+		return []string{strconv.Itoa(len(seen))}, nil
+
+	case "most_common":
+		return mostCommon(values), nil
+
+	case "line":
+		i := c.Line - 1
+		if c.Line < 0 {
+			i = len(values) + c.Line
+		}
+		if c.Line == 0 || i < 0 || i >= len(values) {
+			return nil, fmt.Errorf("line %d is out of range", c.Line)
+		}
+		return []string{values[i]}, nil
+
+	case "max", "min":
+		return extreme(values, c.Op == "max")
+	}
+	return nil, fmt.Errorf("unknown compute op %q", c.Op)
+}
+
+// cutField behaves like cut -f, returning the whole line when it has no
+// delimiter at all.
+func cutField(line, delim string, field int) string {
+	parts := strings.Split(line, delim)
+	if len(parts) == 1 {
+		return line
+	}
+	if field > len(parts) {
+		return ""
+	}
+	return parts[field-1]
+}
+
+// mostCommon returns the most frequent values. A tie accepts any of them,
+// since sort | uniq -c | sort -rn could list either first.
+func mostCommon(values []string) []string {
+	counts := map[string]int{}
+	best := 0
+	for _, value := range values {
+		counts[value]++
+		best = max(best, counts[value])
+	}
+	var answers []string
+	for value, count := range counts {
+		if count == best {
+			answers = append(answers, value)
+		}
+	}
+	return answers
+}
+
+func extreme(values []string, largest bool) ([]string, error) {
+	var best float64
+	var answer string
+	found := false
+	for _, value := range values {
+		n, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			continue
+		}
+		if !found || (largest && n > best) || (!largest && n < best) {
+			best, answer, found = n, strings.TrimSpace(value), true
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("no numbers to compare")
+	}
+	return []string{answer}, nil
+}
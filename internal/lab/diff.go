@@ -0,0 +1,360 @@
+package lab
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bobparsons/rootcamp/internal/types"
+)
+
+type ChangeKind int
+
+const (
+	ChangeAdded ChangeKind = iota
+	ChangeRemoved
+	ChangeModified
+	ChangeMoved
+	ChangeMode
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case ChangeAdded:
+		return "added"
+	case ChangeRemoved:
+		return "removed"
+	case ChangeModified:
+		return "modified"
+	case ChangeMoved:
+		return "moved"
+	case ChangeMode:
+		return "mode changed"
+	}
+	return "changed"
+}
+
+// Change is one difference between the sandbox and its snapshot. Paths are
+// relative to the sandbox root and use forward slashes. A move is reported
+// at its new Path with the old one in From; a moved file that was also
+// edited shows up as removed and added instead. OldMode and NewMode are set
+// whenever the entry exists on both sides, so a modified file can carry a
+// mode change as well.
+type Change struct {
+	Kind    ChangeKind
+	Path    string
+	From    string
+	IsDir   bool
+	OldMode os.FileMode
+	NewMode os.FileMode
+}
+
+type diffEntry struct {
+	mode   os.FileMode
+	digest string
+}
+
+func (e diffEntry) isDir() bool {
+	return e.mode.IsDir()
+}
+
+// Diff compares the sandbox against the snapshot taken when it was built.
+// Changes are sorted by path.
+func Diff(sandboxPath string) ([]Change, error) {
+	before, err := scanTree(snapshotDir(sandboxPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sandbox snapshot: %w", err)
+	}
+	after, err := scanTree(sandboxPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sandbox: %w", err)
+	}
+	return diffTrees(before, after), nil
+}
+
+// scanTree records the type, mode and content digest of everything below
+// root, keyed by slash-separated relative path.
+func scanTree(root string) (map[string]diffEntry, error) {
+	entries := map[string]diffEntry{}
+	err := walkReadable(root, func(p string, info os.FileInfo) error {
+		rel, err := filepath.Rel(root, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		entry := diffEntry{mode: fileMode(info) | info.Mode().Type()}
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			entry.digest = "link:" + target
+		case info.Mode().IsRegular():
+			digest, err := fileDigest(p, info)
+			if err != nil {
+				return err
+			}
+			entry.digest = digest
+		}
+		entries[filepath.ToSlash(rel)] = entry
+		return nil
+	})
+	return entries, err
+}
+
+func fileDigest(p string, info os.FileInfo) (string, error) {
+	if info.Mode().Perm()&0o400 == 0 {
+		os.Chmod(p, info.Mode().Perm()|0o400)
+		defer os.Chmod(p, fileMode(info))
+	}
+	file, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
+
+func diffTrees(before, after map[string]diffEntry) []Change {
+	var changes []Change
+	var removed, added []string
+
+	for p, old := range before {
+		cur, ok := after[p]
+		if !ok {
+			removed = append(removed, p)
+			continue
+		}
+		if old.mode.Type() != cur.mode.Type() || old.digest != cur.digest {
+			changes = append(changes, Change{Kind: ChangeModified, Path: p, IsDir: cur.isDir(), OldMode: old.mode, NewMode: cur.mode})
+		} else if old.mode != cur.mode {
+			changes = append(changes, Change{Kind: ChangeMode, Path: p, IsDir: cur.isDir(), OldMode: old.mode, NewMode: cur.mode})
+		}
+	}
+	for p := range after {
+		if _, ok := before[p]; !ok {
+			added = append(added, p)
+		}
+	}
+	sort.Strings(removed)
+	sort.Strings(added)
+
+	moves := matchMoves(before, after, removed, added)
+	movedFrom := map[string]bool{}
+	movedTo := map[string]bool{}
+	for to, from := range moves {
+		movedFrom[from] = true
+		movedTo[to] = true
+		changes = append(changes, Change{
+			Kind:    ChangeMoved,
+			Path:    to,
+			From:    from,
+			IsDir:   after[to].isDir(),
+			OldMode: before[from].mode,
+			NewMode: after[to].mode,
+		})
+	}
+
+	for _, p := range removed {
+		if !movedFrom[p] && !underMoved(p, movedFrom) {
+			changes = append(changes, Change{Kind: ChangeRemoved, Path: p, IsDir: before[p].isDir(), OldMode: before[p].mode})
+		}
+	}
+	for _, p := range added {
+		if !movedTo[p] && !underMoved(p, movedTo) {
+			changes = append(changes, Change{Kind: ChangeAdded, Path: p, IsDir: after[p].isDir(), NewMode: after[p].mode})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes
+}
+
+// matchMoves pairs removed paths with added ones holding identical content,
+// preferring a pair that kept its name. Whole directories that moved with
+// nothing else changed collapse into a single move. The result maps new
+// paths to old ones.
+func matchMoves(before, after map[string]diffEntry, removed, added []string) map[string]string {
+	byDigest := map[string][]string{}
+	for _, p := range added {
+		if e := after[p]; !e.isDir() {
+			byDigest[e.mode.Type().String()+e.digest] = append(byDigest[e.mode.Type().String()+e.digest], p)
+		}
+	}
+
+	moves := map[string]string{}
+	taken := map[string]bool{}
+	for _, from := range removed {
+		e := before[from]
+		if e.isDir() {
+			continue
+		}
+		candidates := byDigest[e.mode.Type().String()+e.digest]
+		best := ""
+		for _, to := range candidates {
+			if taken[to] {
+				continue
+			}
+			if best == "" || (path.Base(to) == path.Base(from) && path.Base(best) != path.Base(from)) {
+				best = to
+			}
+		}
+		if best != "" {
+			taken[best] = true
+			moves[best] = from
+		}
+	}
+
+	// A directory moved when everything that was in it turns up at the same
+	// place under a new directory and nothing else ended up there.
+	for _, fromDir := range removed {
+		if !before[fromDir].isDir() {
+			continue
+		}
+		for _, toDir := range added {
+			if !after[toDir].isDir() || moves[toDir] != "" {
+				continue
+			}
+			if dirMoved(fromDir, toDir, before, after, moves) {
+				for to, from := range moves {
+					if strings.HasPrefix(to, toDir+"/") && strings.HasPrefix(from, fromDir+"/") {
+						delete(moves, to)
+					}
+				}
+				moves[toDir] = fromDir
+				break
+			}
+		}
+	}
+
+	return moves
+}
+
+func dirMoved(fromDir, toDir string, before, after map[string]diffEntry, moves map[string]string) bool {
+	oldCount := 0
+	for p, e := range before {
+		if !strings.HasPrefix(p, fromDir+"/") {
+			continue
+		}
+		oldCount++
+		rel := strings.TrimPrefix(p, fromDir+"/")
+		target := toDir + "/" + rel
+		if e.isDir() {
+			if t, ok := after[target]; !ok || !t.isDir() {
+				return false
+			}
+			continue
+		}
+		if moves[target] != p {
+			return false
+		}
+	}
+	if oldCount == 0 && path.Base(fromDir) != path.Base(toDir) {
+		return false
+	}
+
+	newCount := 0
+	for p := range after {
+		if strings.HasPrefix(p, toDir+"/") {
+			newCount++
+		}
+	}
+	return oldCount == newCount
+}
+
+func underMoved(p string, moved map[string]bool) bool {
+	for dir := path.Dir(p); dir != "."; dir = path.Dir(dir) {
+		if moved[dir] {
+			return true
+		}
+	}
+	return false
+}
+
+// DescribeChange renders a change as a short line such as
+// "moved report.txt -> archive/report.txt".
+func DescribeChange(c Change) string {
+	name := c.Path
+	if c.IsDir {
+		name += "/"
+	}
+	switch c.Kind {
+	case ChangeMoved:
+		from := c.From
+		if c.IsDir {
+			from += "/"
+		}
+		return fmt.Sprintf("moved %s -> %s", from, name)
+	case ChangeMode:
+		return fmt.Sprintf("changed mode of %s from %s to %s", name, FormatMode(c.OldMode), FormatMode(c.NewMode))
+	}
+	return fmt.Sprintf("%s %s", c.Kind, name)
+}
+
+// FormatMode prints permission bits in octal, with a leading digit for the
+// setuid, setgid and sticky bits when any are set.
+func FormatMode(mode os.FileMode) string {
+	bits := uint32(mode.Perm())
+	if mode&os.ModeSetuid != 0 {
+		bits |= 0o4000
+	}
+	if mode&os.ModeSetgid != 0 {
+		bits |= 0o2000
+	}
+	if mode&os.ModeSticky != 0 {
+		bits |= 0o1000
+	}
+	if bits > 0o777 {
+		return fmt.Sprintf("%04o", bits)
+	}
+	return fmt.Sprintf("%03o", bits)
+}
+
+// explainFailure adds what the learner actually did around a failing state
+// check, such as copying into backup/ when the task wanted archive/.
+func explainFailure(req types.Requirement, sandboxPath string) string {
+	if req.Path == "" || sandboxPath == "" {
+		return req.Description
+	}
+	if strings.HasPrefix(req.Validator, "archive_") {
+		if msg, ok := explainArchiveFailure(req, sandboxPath); ok {
+			return msg
+		}
+	}
+	changes, err := Diff(sandboxPath)
+	if err != nil {
+		return req.Description
+	}
+
+	want := strings.Trim(filepath.ToSlash(filepath.Clean("/"+req.Path)), "/")
+	for _, c := range changes {
+		if c.Path == want || c.From == want {
+			return fmt.Sprintf("%s (sandbox shows: %s)", req.Description, DescribeChange(c))
+		}
+	}
+	// Something with the same name somewhere else, or failing that something
+	// new right next to where it was expected.
+	for _, c := range changes {
+		if (c.Kind == ChangeAdded || c.Kind == ChangeMoved) && path.Base(c.Path) == path.Base(want) {
+			return fmt.Sprintf("%s (sandbox shows: %s)", req.Description, DescribeChange(c))
+		}
+	}
+	for _, c := range changes {
+		if (c.Kind == ChangeAdded || c.Kind == ChangeMoved) && path.Dir(c.Path) == path.Dir(want) {
+			return fmt.Sprintf("%s (sandbox shows: %s)", req.Description, DescribeChange(c))
+		}
+	}
+	return req.Description
+}
@@ -0,0 +1,226 @@
+package lab
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bobparsons/rootcamp/internal/types"
+)
+
+// fixtureTime stamps every archive member and gzip header, so a fixture
+// comes out byte for byte the same each time a sandbox is built.
+var fixtureTime = time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+
+func writeFixtures(root string, fixtures []types.SandboxFixture) error {
+	for _, fixture := range fixtures {
+		data, err := buildFixture(fixture)
+		if err != nil {
+			return fmt.Errorf("failed to build fixture %s: %w", fixture.Path, err)
+		}
+		if err := writeSandboxFile(root, fixture.Path, string(data), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func buildFixture(fixture types.SandboxFixture) ([]byte, error) {
+	switch fixture.Type {
+	case "base64":
+		data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(fixture.Content), ""))
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 content: %w", err)
+		}
+		return data, nil
+	case "gzip":
+		return buildGzip(fixture)
+	case "tar":
+		return buildTar(fixture)
+	case "tar.gz", "tgz":
+		data, err := buildTar(fixture)
+		if err != nil {
+			return nil, err
+		}
+		return gzipBytes(data, "")
+	case "zip":
+		return buildZip(fixture)
+	}
+	return nil, fmt.Errorf("unknown fixture type %q", fixture.Type)
+}
+
+// buildGzip compresses either the fixture's Content or its one nested
+// fixture. Like gzip itself, the header records the name without ".gz".
+func buildGzip(fixture types.SandboxFixture) ([]byte, error) {
+	data := []byte(fixture.Content)
+	if len(fixture.Fixtures) > 1 {
+		return nil, fmt.Errorf("gzip holds a single file")
+	}
+	if len(fixture.Fixtures) == 1 {
+		inner, err := buildFixture(fixture.Fixtures[0])
+		if err != nil {
+			return nil, err
+		}
+		data = inner
+	}
+	return gzipBytes(data, strings.TrimSuffix(path.Base(fixture.Path), ".gz"))
+}
+
+func gzipBytes(data []byte, name string) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Name = name
+	zw.ModTime = fixtureTime
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type fixtureMember struct {
+	name  string
+	isDir bool
+	mode  os.FileMode
+	data  []byte
+}
+
+// archiveMembers flattens an archive fixture into its entries, sorted by
+// name, with a directory entry for every parent the way tar and zip record
+// them when given a directory.
+func archiveMembers(fixture types.SandboxFixture) ([]fixtureMember, error) {
+	files := map[string][]byte{}
+	for name, content := range fixture.Files {
+		files[memberName(name)] = []byte(content)
+	}
+	for _, nested := range fixture.Fixtures {
+		data, err := buildFixture(nested)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build nested fixture %s: %w", nested.Path, err)
+		}
+		files[memberName(nested.Path)] = data
+	}
+
+	dirs := map[string]bool{}
+	for _, dir := range fixture.Dirs {
+		dirs[memberName(dir)] = true
+	}
+	for name := range files {
+		for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+			dirs[dir] = true
+		}
+	}
+
+	modes := map[string]os.FileMode{}
+	for name, value := range fixture.Modes {
+		mode, err := ParseMode(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mode for %s: %w", name, err)
+		}
+		name = memberName(name)
+		if _, ok := files[name]; !ok && !dirs[name] {
+			return nil, fmt.Errorf("mode given for %s, which is not in the archive", name)
+		}
+		modes[name] = mode
+	}
+
+	var members []fixtureMember
+	for dir := range dirs {
+		mode, ok := modes[dir]
+		if !ok {
+			mode = 0755
+		}
+		members = append(members, fixtureMember{name: dir, isDir: true, mode: mode})
+	}
+	for name, data := range files {
+		mode, ok := modes[name]
+		if !ok {
+			mode = 0644
+		}
+		members = append(members, fixtureMember{name: name, mode: mode, data: data})
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].name < members[j].name
+	})
+	return members, nil
+}
+
+func buildTar(fixture types.SandboxFixture) ([]byte, error) {
+	members, err := archiveMembers(fixture)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, m := range members {
+		hdr := &tar.Header{
+			Name:    m.name,
+			Mode:    int64(m.mode.Perm()),
+			ModTime: fixtureTime,
+			Format:  tar.FormatUSTAR,
+		}
+		if m.isDir {
+			hdr.Typeflag = tar.TypeDir
+			hdr.Name += "/"
+		} else {
+			hdr.Typeflag = tar.TypeReg
+			hdr.Size = int64(len(m.data))
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(m.data); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func buildZip(fixture types.SandboxFixture) ([]byte, error) {
+	members, err := archiveMembers(fixture)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, m := range members {
+		hdr := &zip.FileHeader{
+			Name:     m.name,
+			Method:   zip.Deflate,
+			Modified: fixtureTime,
+		}
+		if m.isDir {
+			hdr.Name += "/"
+			hdr.Method = zip.Store
+			hdr.SetMode(os.ModeDir | m.mode)
+		} else {
+			hdr.SetMode(m.mode)
+		}
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(m.data); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,222 @@
+package lab
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bobparsons/rootcamp/internal/types"
+)
+
+// maxGeneratedLines keeps a typo in a lesson from filling the disk.
+const maxGeneratedLines = 200000
+
+func writeGenerated(root string, files []types.GeneratedFile) error {
+	for _, gen := range files {
+		content, err := generateContent(gen)
+		if err != nil {
+			return fmt.Errorf("failed to generate %s: %w", gen.Path, err)
+		}
+		if err := writeSandboxFile(root, gen.Path, content, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func generateContent(gen types.GeneratedFile) (string, error) {
+	if gen.Lines <= 0 || gen.Lines > maxGeneratedLines {
+		return "", fmt.Errorf("lines must be between 1 and %d", maxGeneratedLines)
+	}
+	seed := gen.Seed
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+	rng := rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))
+
+	var lines []string
+	switch gen.Kind {
+	case "access_log":
+		lines = generateAccessLog(rng, gen.Lines)
+	case "csv":
+		var err error
+		if lines, err = generateCSV(rng, gen); err != nil {
+			return "", err
+		}
+	case "words":
+		lines = generateWords(rng, gen.Lines, max(gen.Words, 1))
+	default:
+		return "", fmt.Errorf("unknown generator %q", gen.Kind)
+	}
+	return strings.Join(lines, "\n") + "\n", nil
+}
+
+var (
+	generatedStart = time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+
+	logPaths = []string{
+		"/", "/index.html", "/login", "/logout", "/api/users", "/api/orders",
+		"/api/products", "/static/app.js", "/static/style.css", "/images/logo.png",
+		"/admin", "/search", "/cart", "/checkout", "/favicon.ico",
+	}
+	logMethods  = []string{"GET", "GET", "GET", "GET", "POST", "POST", "PUT", "DELETE"}
+	logStatuses = []int{200, 200, 200, 200, 200, 200, 200, 304, 304, 301, 404, 404, 403, 500}
+
+	wordList = []string{
+		"apple", "river", "stone", "cloud", "tiger", "lemon", "forest", "pixel",
+		"rocket", "garden", "silver", "candle", "planet", "window", "bridge",
+		"orange", "shadow", "marble", "falcon", "meadow", "copper", "harbor",
+		"violet", "summit", "anchor", "breeze", "cactus", "dragon", "ember",
+		"glacier", "island", "jungle", "kernel", "lantern", "magnet", "nectar",
+		"oyster", "pepper", "quartz", "raven", "saddle", "timber", "umbrella",
+		"valley", "walnut", "yonder", "zephyr", "badger", "cobalt", "dune",
+	}
+
+	firstNames = []string{
+		"alice", "bob", "carol", "dave", "erin", "frank", "grace", "heidi",
+		"ivan", "judy", "mallory", "niaj", "olivia", "peggy", "rupert",
+		"sybil", "trent", "uma", "victor", "wendy",
+	}
+)
+
+// generateAccessLog writes Common Log Format lines. Client addresses follow
+// a Zipf distribution, as real traffic does, so a handful of clients stand
+// out when counted.
+func generateAccessLog(rng *rand.Rand, n int) []string {
+	clients := make([]string, 40)
+	for i := range clients {
+		clients[i] = randomIP(rng)
+	}
+	zipf := rand.NewZipf(rng, 1.2, 2, uint64(len(clients)-1))
+
+	lines := make([]string, n)
+	at := generatedStart
+	for i := range lines {
+		at = at.Add(time.Duration(rng.IntN(90)+1) * time.Second)
+		status := logStatuses[rng.IntN(len(logStatuses))]
+		size := 0
+		if status != 304 {
+			size = 200 + rng.IntN(20000)
+		}
+		lines[i] = fmt.Sprintf("%s - - [%s] \"%s %s HTTP/1.1\" %d %d",
+			clients[zipf.Uint64()],
+			at.Format("02/Jan/2006:15:04:05 -0700"),
+			logMethods[rng.IntN(len(logMethods))],
+			logPaths[rng.IntN(len(logPaths))],
+			status,
+			size,
+		)
+	}
+	return lines
+}
+
+func randomIP(rng *rand.Rand) string {
+	return fmt.Sprintf("%d.%d.%d.%d", []int{10, 172, 192}[rng.IntN(3)], rng.IntN(256), rng.IntN(256), 1+rng.IntN(254))
+}
+
+func generateWords(rng *rand.Rand, n, perLine int) []string {
+	lines := make([]string, n)
+	words := make([]string, perLine)
+	for i := range lines {
+		for j := range words {
+			words[j] = wordList[rng.IntN(len(wordList))]
+		}
+		lines[i] = strings.Join(words, " ")
+	}
+	return lines
+}
+
+type csvColumn struct {
+	name string
+	kind string
+	args string
+}
+
+// generateCSV fills one row per line from column specs such as "id:seq",
+// "amount:int:10-500", "price:price:5-250", "status:choice:ok|failed",
+// "name:name", "word:word", "day:date" and "client:ip".
+func generateCSV(rng *rand.Rand, gen types.GeneratedFile) ([]string, error) {
+	if len(gen.Columns) == 0 {
+		return nil, fmt.Errorf("csv needs at least one column")
+	}
+	delim := gen.Delimiter
+	if delim == "" {
+		delim = ","
+	}
+
+	columns := make([]csvColumn, len(gen.Columns))
+	header := make([]string, len(gen.Columns))
+	for i, spec := range gen.Columns {
+		parts := strings.SplitN(spec, ":", 3)
+		col := csvColumn{name: parts[0], kind: "word"}
+		if len(parts) > 1 {
+			col.kind = parts[1]
+		}
+		if len(parts) > 2 {
+			col.args = parts[2]
+		}
+		columns[i] = col
+		header[i] = col.name
+	}
+
+	var lines []string
+	if !gen.NoHeader {
+		lines = append(lines, strings.Join(header, delim))
+	}
+	row := make([]string, len(columns))
+	for n := 0; n < gen.Lines; n++ {
+		for i, col := range columns {
+			value, err := csvValue(rng, col, n)
+			if err != nil {
+				return nil, err
+			}
+			row[i] = value
+		}
+		lines = append(lines, strings.Join(row, delim))
+	}
+	return lines, nil
+}
+
+func csvValue(rng *rand.Rand, col csvColumn, row int) (string, error) {
+	switch col.kind {
+	case "seq":
+		return strconv.Itoa(row + 1), nil
+	case "int", "price":
+		lo, hi, err := parseRange(col.args)
+		if err != nil {
+			return "", fmt.Errorf("column %s: %w", col.name, err)
+		}
+		if col.kind == "price" {
+			cents := lo*100 + rng.IntN((hi-lo)*100+1)
+			return fmt.Sprintf("%d.%02d", cents/100, cents%100), nil
+		}
+		return strconv.Itoa(lo + rng.IntN(hi-lo+1)), nil
+	case "choice":
+		choices := strings.Split(col.args, "|")
+		if col.args == "" {
+			return "", fmt.Errorf("column %s: choice needs values", col.name)
+		}
+		return choices[rng.IntN(len(choices))], nil
+	case "word":
+		return wordList[rng.IntN(len(wordList))], nil
+	case "name":
+		return firstNames[rng.IntN(len(firstNames))], nil
+	case "date":
+		return generatedStart.AddDate(0, 0, row/3).Format("2006-01-02"), nil
+	case "ip":
+		return randomIP(rng), nil
+	}
+	return "", fmt.Errorf("column %s: unknown type %q", col.name, col.kind)
+}
+
+func parseRange(value string) (int, int, error) {
+	loText, hiText, ok := strings.Cut(value, "-")
+	lo, errLo := strconv.Atoi(loText)
+	hi, errHi := strconv.Atoi(hiText)
+	if !ok || errLo != nil || errHi != nil || hi < lo {
+		return 0, 0, fmt.Errorf("%q is not a range like 1-100", value)
+	}
+	return lo, hi, nil
+}
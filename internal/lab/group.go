@@ -0,0 +1,101 @@
+package lab
+
+import (
+	"strings"
+
+	"github.com/bobparsons/rootcamp/internal/types"
+)
+
+// IsGroup reports whether a requirement combines others through all, any or
+// not instead of running a validator of its own.
+func IsGroup(req types.Requirement) bool {
+	return len(req.All) > 0 || len(req.Any) > 0 || req.Not != nil
+}
+
+func groupMembers(req types.Requirement) []types.Requirement {
+	members := append(append([]types.Requirement{}, req.All...), req.Any...)
+	if req.Not != nil {
+		members = append(members, *req.Not)
+	}
+	return members
+}
+
+// leafRequirements flattens groups into the checks they are built from.
+func leafRequirements(reqs []types.Requirement) []types.Requirement {
+	var leaves []types.Requirement
+	for _, req := range reqs {
+		if IsGroup(req) {
+			leaves = append(leaves, leafRequirements(groupMembers(req))...)
+			continue
+		}
+		leaves = append(leaves, req)
+	}
+	return leaves
+}
+
+// checkRequirement returns every reason a requirement fails, or nothing if
+// it passes, along with its score. An all group reports each member that
+// failed. An any or not group reports its own description when it has one,
+// since the members it offers are alternatives rather than things to fix
+// one by one.
+func checkRequirement(req types.Requirement, userInput, sandboxPath string) ([]string, float64) {
+	if !IsGroup(req) {
+		result := runValidator(req, userInput, sandboxPath)
+		if result.Passed {
+			return nil, 1
+		}
+		return []string{result.Message}, result.Score
+	}
+
+	var failures []string
+	var scores []float64
+	for _, member := range req.All {
+		reasons, score := checkRequirement(member, userInput, sandboxPath)
+		failures = append(failures, reasons...)
+		scores = append(scores, score)
+	}
+
+	if len(req.Any) > 0 {
+		var missed []string
+		best := 0.0
+		for _, member := range req.Any {
+			reasons, score := checkRequirement(member, userInput, sandboxPath)
+			best = max(best, score)
+			if len(reasons) == 0 {
+				missed = nil
+				break
+			}
+			missed = append(missed, reasons...)
+		}
+		scores = append(scores, best)
+		if len(missed) > 0 {
+			if req.Description != "" {
+				failures = append(failures, req.Description)
+			} else {
+				failures = append(failures, "one of: "+strings.Join(missed, ", or "))
+			}
+		}
+	}
+
+	if req.Not != nil {
+		if reasons, _ := checkRequirement(*req.Not, userInput, sandboxPath); len(reasons) == 0 {
+			scores = append(scores, 0)
+			if req.Description != "" {
+				failures = append(failures, req.Description)
+			} else {
+				failures = append(failures, "not: "+req.Not.Description)
+			}
+		} else {
+			scores = append(scores, 1)
+		}
+	}
+
+	if len(failures) == 0 {
+		return nil, 1
+	}
+	total := 0.0
+	for _, score := range scores {
+		total += score
+	}
+	return failures, total / float64(len(scores))
+}
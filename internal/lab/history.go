@@ -0,0 +1,366 @@
+package lab
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bobparsons/rootcamp/internal/types"
+)
+
+// HistoryRequirement marks requirements that are graded on the commands the
+// learner ran in the lab shell. Like sandbox state checks they must always
+// pass, on top of any answer the lesson asks for.
+const HistoryRequirement = "command_history"
+
+func IsHistoryRequirement(req types.Requirement) bool {
+	return req.Type == HistoryRequirement
+}
+
+func NeedsHistory(lesson types.Lesson) bool {
+	for _, req := range AllRequirements(lesson) {
+		if IsHistoryRequirement(req) {
+			return true
+		}
+	}
+	return false
+}
+
+// StateDir holds RootCamp's own files for a sandbox, such as shell hooks and
+// the command history, outside the directory tree the learner works in.
+func StateDir(sandboxPath string) string {
+	return sandboxPath + ".state"
+}
+
+func historyPath(sandboxPath string) string {
+	return filepath.Join(StateDir(sandboxPath), "history")
+}
+
+// ReadHistory returns the commands recorded for a sandbox in the order they
+// were run. Malformed lines are skipped.
+func ReadHistory(sandboxPath string) ([]types.CommandRecord, error) {
+	if sandboxPath == "" {
+		return nil, nil
+	}
+
+	file, err := os.Open(historyPath(sandboxPath))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open command history: %w", err)
+	}
+	defer file.Close()
+
+	var records []types.CommandRecord
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), "\t", 4)
+		if len(fields) != 4 {
+			continue
+		}
+		unix, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		status, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue
+		}
+		command := strings.TrimSpace(fields[3])
+		if command == "" {
+			continue
+		}
+		records = append(records, types.CommandRecord{
+			ExecutedAt: time.Unix(unix, 0),
+			ExitStatus: status,
+			Dir:        fields[2],
+			Command:    command,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read command history: %w", err)
+	}
+
+	return records, nil
+}
+
+func historyCommands(sandboxPath string) []string {
+	records, err := ReadHistory(sandboxPath)
+	if err != nil {
+		return nil
+	}
+	commands := make([]string, len(records))
+	for i, record := range records {
+		commands[i] = record.Command
+	}
+	return commands
+}
+
+func validateCommandUsed(name, sandboxPath string) bool {
+	for _, line := range historyCommands(sandboxPath) {
+		for _, words := range splitCommandLine(line) {
+			if commandName(words) == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func validateCommandMatchesRegex(pattern, sandboxPath string) bool {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return false
+	}
+	for _, line := range historyCommands(sandboxPath) {
+		if re.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateFlagUsed checks that the command was run with the flag, or with one
+// of the listed aliases. Short flags also count inside a bundle, so -i is
+// found in grep -in.
+func validateFlagUsed(command, flag string, aliases []string, sandboxPath string) bool {
+	flags := append([]string{flag}, aliases...)
+	for _, line := range historyCommands(sandboxPath) {
+		for _, words := range splitCommandLine(line) {
+			if command != "" && commandName(words) != command {
+				continue
+			}
+			for _, arg := range commandArgs(words) {
+				if arg == "--" {
+					break
+				}
+				for _, f := range flags {
+					if argHasFlag(arg, f) {
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+func validateCommandEquivalent(expected, sandboxPath string) bool {
+	for _, line := range historyCommands(sandboxPath) {
+		if CommandEquivalent(expected, line) {
+			return true
+		}
+	}
+	return false
+}
+
+// CommandEquivalent reports whether the command line runs the expected
+// command, possibly as part of a longer line. Quoting does not matter, and
+// neither does the order of flags or how short flags are bundled, so
+// "ls -la" matches "ls -a -l". A flag's value may move with its flag, as in
+// "cut -f 2 -d ,", but other arguments must stay in order.
+func CommandEquivalent(expected, line string) bool {
+	want := splitCommandLine(expected)
+	got := splitCommandLine(line)
+	if len(want) == 0 {
+		return false
+	}
+	for start := 0; start+len(want) <= len(got); start++ {
+		matched := true
+		for i, words := range want {
+			if !simpleCommandEquivalent(words, got[start+i]) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+func simpleCommandEquivalent(a, b []string) bool {
+	if commandName(a) != commandName(b) {
+		return false
+	}
+	argsA, argsB := commandArgs(a), commandArgs(b)
+	return slices.Equal(canonicalArgs(argsA, false), canonicalArgs(argsB, false)) ||
+		slices.Equal(canonicalArgs(argsA, true), canonicalArgs(argsB, true))
+}
+
+// canonicalArgs puts a command's arguments in a form where flag order does
+// not matter: each short flag on its own, flags sorted, then the remaining
+// arguments in order. With withValues, the argument after a flag is taken
+// as its value and sorted along with it.
+func canonicalArgs(args []string, withValues bool) []string {
+	var flags, operands []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if len(arg) < 2 || arg[0] != '-' || arg == "--" {
+			operands = append(operands, arg)
+			continue
+		}
+
+		var expanded []string
+		if arg[1] == '-' {
+			expanded = []string{arg}
+		} else {
+			for _, r := range arg[1:] {
+				expanded = append(expanded, "-"+string(r))
+			}
+		}
+		if withValues && i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+			i++
+			expanded[len(expanded)-1] += " " + args[i]
+		}
+		flags = append(flags, expanded...)
+	}
+	slices.Sort(flags)
+	return append(flags, operands...)
+}
+
+func argHasFlag(arg, flag string) bool {
+	if strings.HasPrefix(flag, "--") {
+		return arg == flag || strings.HasPrefix(arg, flag+"=")
+	}
+	if len(flag) != 2 || flag[0] != '-' {
+		return arg == flag
+	}
+	if len(arg) < 2 || arg[0] != '-' || arg[1] == '-' {
+		return false
+	}
+	return strings.ContainsRune(arg[1:], rune(flag[1]))
+}
+
+var envAssignment = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*=`)
+
+// commandIndex finds the program a simple command runs, skipping variable
+// assignments and wrappers such as sudo. It returns -1 if there is none.
+func commandIndex(words []string) int {
+	for i, word := range words {
+		if envAssignment.MatchString(word) {
+			continue
+		}
+		switch word {
+		case "sudo", "command", "builtin", "exec", "time", "nohup", "env":
+			continue
+		}
+		return i
+	}
+	return -1
+}
+
+// CommandName returns the program the command line runs first, or "" if it
+// runs none.
+func CommandName(line string) string {
+	commands := splitCommandLine(line)
+	if len(commands) == 0 {
+		return ""
+	}
+	return commandName(commands[0])
+}
+
+// RunsProgram reports whether any command on the line runs the program,
+// whether directly, further along a pipeline or through a wrapper such as
+// sudo.
+func RunsProgram(line, program string) bool {
+	for _, words := range splitCommandLine(line) {
+		end := len(words)
+		if i := commandIndex(words); i >= 0 {
+			end = i + 1
+		}
+		for _, word := range words[:end] {
+			if filepath.Base(word) == program {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func commandName(words []string) string {
+	i := commandIndex(words)
+	if i < 0 {
+		return ""
+	}
+	return filepath.Base(words[i])
+}
+
+func commandArgs(words []string) []string {
+	i := commandIndex(words)
+	if i < 0 {
+		return nil
+	}
+	return words[i+1:]
+}
+
+// splitCommandLine breaks a shell command line into the words of each simple
+// command, splitting on pipes, lists and subshell parentheses. Quotes and
+// backslashes are honoured but expansions are left as written.
+func splitCommandLine(line string) [][]string {
+	var commands [][]string
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+
+	endWord := func() {
+		if inWord {
+			words = append(words, word.String())
+			word.Reset()
+			inWord = false
+		}
+	}
+	endCommand := func() {
+		endWord()
+		if len(words) > 0 {
+			commands = append(commands, words)
+			words = nil
+		}
+	}
+
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else if r == '\\' && quote == '"' && i+1 < len(runes) {
+				i++
+				word.WriteRune(runes[i])
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == '\\' && i+1 < len(runes):
+			i++
+			word.WriteRune(runes[i])
+			inWord = true
+		case r == ' ' || r == '\t':
+			endWord()
+		case r == '|' || r == ';' || r == '&' || r == '(' || r == ')' || r == '`':
+			endCommand()
+		case r == '$' && i+1 < len(runes) && runes[i+1] == '(':
+			endCommand()
+			i++
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	endCommand()
+
+	return commands
+}
@@ -0,0 +1,11 @@
+package lab
+
+// InitCommand is the hidden first argument that makes the rootcamp binary
+// act as the setup process inside an isolated lab. main must hand control to
+// RunInit before doing anything else when it sees it.
+const InitCommand = "__lab-init"
+
+const (
+	initModeShell = "shell"
+	initModeProbe = "probe"
+)
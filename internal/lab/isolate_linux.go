@@ -0,0 +1,276 @@
+//go:build linux
+
+package lab
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/bobparsons/rootcamp/internal/types"
+)
+
+var (
+	probeOnce sync.Once
+	probeErr  error
+)
+
+// Isolate rewrites a lab shell command so it runs in new user and mount
+// namespaces, and a new PID namespace when the lesson has no helper
+// processes for the learner to find. Inside, the learner is root, but every
+// mount except the sandbox and its state directory is read-only, so mistakes
+// cannot reach the real home directory.
+//
+// The command is re-executed through the rootcamp binary, which sets up the
+// mounts from inside the namespaces before exec'ing the shell. The first
+// call checks that the kernel allows all of this and the error is kept for
+// later calls.
+func Isolate(c *exec.Cmd, sandboxPath string, lesson types.Lesson) error {
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find rootcamp executable: %w", err)
+	}
+
+	tmpDir := filepath.Join(StateDir(sandboxPath), "tmp")
+	if err := os.MkdirAll(tmpDir, 0700); err != nil {
+		return fmt.Errorf("failed to create isolated temp directory: %w", err)
+	}
+
+	pidns := len(lesson.Sandbox.Processes) == 0
+	env := c.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	env = append(env,
+		"ROOTCAMP_ISOLATED=1",
+		"ROOTCAMP_SANDBOX="+sandboxPath,
+		"ROOTCAMP_STATE="+StateDir(sandboxPath),
+		"ROOTCAMP_START="+GetStartPath(sandboxPath, lesson),
+		"TMPDIR="+tmpDir,
+	)
+
+	probeOnce.Do(func() {
+		probeErr = probeIsolation(self, env, pidns)
+	})
+	if probeErr != nil {
+		return fmt.Errorf("sandbox isolation is not available: %w", probeErr)
+	}
+
+	c.Args = append([]string{"rootcamp", InitCommand, initModeShell, c.Path}, c.Args...)
+	c.Path = self
+	c.Env = env
+	c.SysProcAttr = namespaceAttrs(pidns)
+	return nil
+}
+
+func namespaceAttrs(pidns bool) *syscall.SysProcAttr {
+	flags := uintptr(syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS)
+	if pidns {
+		flags |= syscall.CLONE_NEWPID
+	}
+	return &syscall.SysProcAttr{
+		Cloneflags:                 flags,
+		UidMappings:                []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getuid(), Size: 1}},
+		GidMappings:                []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getgid(), Size: 1}},
+		GidMappingsEnableSetgroups: false,
+	}
+}
+
+// probeIsolation runs the setup steps once without starting a shell. Some
+// distributions allow creating a user namespace but deny the mounts inside
+// it, which only shows up this way.
+func probeIsolation(self string, env []string, pidns bool) error {
+	c := exec.Command(self, InitCommand, initModeProbe)
+	c.Args[0] = "rootcamp"
+	c.Env = env
+	c.SysProcAttr = namespaceAttrs(pidns)
+	var stderr bytes.Buffer
+	c.Stderr = &stderr
+	if err := c.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%s", msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// RunInit is the entry point for the setup process inside the namespaces.
+// It never returns.
+func RunInit(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "missing lab init mode")
+		os.Exit(2)
+	}
+
+	if err := setupMounts(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if args[0] == initModeProbe {
+		os.Exit(0)
+	}
+	if args[0] != initModeShell || len(args) < 3 {
+		fmt.Fprintln(os.Stderr, "invalid lab init arguments")
+		os.Exit(2)
+	}
+
+	// The sandbox was mounted over itself, so the inherited working directory
+	// still points at the read-only copy underneath.
+	if err := os.Chdir(os.Getenv("ROOTCAMP_START")); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to enter sandbox: %v\n", err)
+		os.Exit(1)
+	}
+
+	err := syscall.Exec(args[1], args[2:], os.Environ())
+	fmt.Fprintf(os.Stderr, "failed to start shell: %v\n", err)
+	os.Exit(1)
+}
+
+// Mounts under these are pseudo filesystems that need real privileges to
+// change anyway, and /dev has to stay usable for the terminal.
+var keepWritable = []string{"/proc", "/sys", "/dev"}
+
+func setupMounts() error {
+	sandboxPath := os.Getenv("ROOTCAMP_SANDBOX")
+	stateDir := os.Getenv("ROOTCAMP_STATE")
+	if !isSandboxPath(sandboxPath) || stateDir != StateDir(sandboxPath) {
+		return fmt.Errorf("invalid sandbox path: %s", sandboxPath)
+	}
+
+	if err := syscall.Mount("", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("failed to make mounts private: %w", err)
+	}
+
+	mounts, err := mountPoints()
+	if err != nil {
+		return err
+	}
+	for _, mount := range mounts {
+		if underAny(mount, keepWritable) {
+			continue
+		}
+		if err := remount(mount, true); err != nil {
+			return fmt.Errorf("failed to make %s read-only: %w", mount, err)
+		}
+	}
+
+	for _, dir := range []string{sandboxPath, stateDir} {
+		if err := syscall.Mount(dir, dir, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+			return fmt.Errorf("failed to bind %s: %w", dir, err)
+		}
+		if err := remount(dir, false); err != nil {
+			return fmt.Errorf("failed to make %s writable: %w", dir, err)
+		}
+	}
+
+	// Only possible in a new PID namespace. Without it ps would still list
+	// the host's processes, which is harmless.
+	if os.Getpid() == 1 {
+		syscall.Mount("proc", "/proc", "proc", syscall.MS_NOSUID|syscall.MS_NODEV|syscall.MS_NOEXEC, "")
+	}
+
+	return nil
+}
+
+// remount changes whether a bind mount is read-only. Flags such as nosuid
+// that came from outside the user namespace are locked and must be passed
+// back unchanged or the kernel refuses the remount.
+func remount(path string, readonly bool) error {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return err
+	}
+
+	flags := uintptr(syscall.MS_REMOUNT | syscall.MS_BIND)
+	if readonly {
+		flags |= syscall.MS_RDONLY
+	}
+	for statFlag, mountFlag := range lockedFlags {
+		if int64(st.Flags)&statFlag != 0 {
+			flags |= mountFlag
+		}
+	}
+	return syscall.Mount("", path, "", flags, "")
+}
+
+// statfs reports mount options as ST_* bits, which mostly but not always
+// match the MS_* flags mount takes.
+var lockedFlags = map[int64]uintptr{
+	0x0002: syscall.MS_NOSUID,
+	0x0004: syscall.MS_NODEV,
+	0x0008: syscall.MS_NOEXEC,
+	0x0400: syscall.MS_NOATIME,
+	0x0800: syscall.MS_NODIRATIME,
+	0x1000: syscall.MS_RELATIME,
+}
+
+// mountPoints lists every mount visible to the process, parents first.
+func mountPoints() ([]string, error) {
+	file, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mounts: %w", err)
+	}
+	defer file.Close()
+
+	seen := map[string]bool{}
+	var mounts []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 {
+			continue
+		}
+		mount := unescapeMount(fields[4])
+		if !seen[mount] {
+			seen[mount] = true
+			mounts = append(mounts, mount)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read mounts: %w", err)
+	}
+
+	sort.Slice(mounts, func(i, j int) bool {
+		return strings.Count(mounts[i], "/") < strings.Count(mounts[j], "/")
+	})
+	return mounts, nil
+}
+
+// unescapeMount decodes the octal escapes mountinfo uses for spaces and
+// other awkward characters in paths.
+func unescapeMount(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+func underAny(path string, roots []string) bool {
+	for _, root := range roots {
+		if path == root || strings.HasPrefix(path, root+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,21 @@
+//go:build !linux
+
+package lab
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/bobparsons/rootcamp/internal/types"
+)
+
+// Isolate is only available on Linux.
+func Isolate(c *exec.Cmd, sandboxPath string, lesson types.Lesson) error {
+	return fmt.Errorf("sandbox isolation requires Linux")
+}
+
+func RunInit(args []string) {
+	fmt.Fprintln(os.Stderr, "sandbox isolation requires Linux")
+	os.Exit(1)
+}
@@ -0,0 +1,15 @@
+//go:build unix
+
+package lab
+
+import (
+	"os"
+	"syscall"
+)
+
+// tryLock takes an exclusive lock on the file without waiting, failing if
+// another process already holds it. The lock is released when the file is
+// closed.
+func tryLock(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+}
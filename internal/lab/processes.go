@@ -0,0 +1,221 @@
+package lab
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/bobparsons/rootcamp/internal/types"
+)
+
+// helperProcess is a background process a lesson runs for the learner to
+// find, inspect or kill. Each one leads its own process group so cleanup can
+// take down anything it spawned.
+type helperProcess struct {
+	name   string
+	pid    int
+	done   chan struct{}
+	signal syscall.Signal
+	status int
+}
+
+func processesDir(sandboxPath string) string {
+	return filepath.Join(StateDir(sandboxPath), "processes")
+}
+
+// startProcesses launches the lesson's helper processes in the start
+// directory. Each script runs under bash with the helper's name as argv[0],
+// so it shows up by name in ps and top. The PIDs go into the sandbox
+// manifest so Reap can find them after a crash.
+func startProcesses(sandboxPath string, lesson types.Lesson) error {
+	if len(lesson.Sandbox.Processes) == 0 {
+		return nil
+	}
+
+	dir := processesDir(sandboxPath)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create process directory: %w", err)
+	}
+
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		return fmt.Errorf("failed to find bash: %w", err)
+	}
+
+	for _, proc := range lesson.Sandbox.Processes {
+		if proc.Name == "" || proc.Name != filepath.Base(proc.Name) {
+			return fmt.Errorf("invalid process name %q", proc.Name)
+		}
+
+		scriptPath := filepath.Join(dir, proc.Name)
+		if err := os.WriteFile(scriptPath, []byte(proc.Script), 0600); err != nil {
+			return fmt.Errorf("failed to write script for %s: %w", proc.Name, err)
+		}
+
+		c := &exec.Cmd{
+			Path: bash,
+			Args: []string{proc.Name, scriptPath},
+			Dir:  GetStartPath(sandboxPath, lesson),
+			Env: append(os.Environ(),
+				"ROOTCAMP_SANDBOX="+sandboxPath,
+				"ROOTCAMP_PROCESS="+proc.Name,
+			),
+			SysProcAttr: helperProcAttr(),
+		}
+		if err := c.Start(); err != nil {
+			return fmt.Errorf("failed to start %s: %w", proc.Name, err)
+		}
+
+		helper := &helperProcess{
+			name: proc.Name,
+			pid:  c.Process.Pid,
+			done: make(chan struct{}),
+		}
+		go helper.wait(c)
+
+		labsMu.Lock()
+		entry := labs[sandboxPath]
+		if entry != nil {
+			entry.helpers = append(entry.helpers, helper)
+			entry.manifest.Processes = append(entry.manifest.Processes, ManifestProcess{
+				Name: helper.name,
+				PID:  helper.pid,
+			})
+		}
+		labsMu.Unlock()
+
+		if entry != nil {
+			if err := writeManifest(entry.manifest); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (h *helperProcess) wait(c *exec.Cmd) {
+	c.Wait()
+	if ws, ok := c.ProcessState.Sys().(syscall.WaitStatus); ok {
+		if ws.Signaled() {
+			h.signal = ws.Signal()
+		}
+		h.status = ws.ExitStatus()
+	}
+	close(h.done)
+}
+
+func (h *helperProcess) exited() bool {
+	select {
+	case <-h.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// stopProcesses kills every helper's process group, including any children
+// left behind after the learner killed the helper itself, and waits for the
+// helpers to be reaped.
+func stopProcesses(sandboxPath string) {
+	labsMu.Lock()
+	var running []*helperProcess
+	if entry := labs[sandboxPath]; entry != nil {
+		running = entry.helpers
+		entry.helpers = nil
+	}
+	labsMu.Unlock()
+
+	for _, helper := range running {
+		killProcessGroup(helper.pid)
+	}
+	for _, helper := range running {
+		select {
+		case <-helper.done:
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
+
+// restartProcesses replaces the lesson's helpers with fresh copies, so a
+// reset lab also gets back any process the learner already killed.
+func restartProcesses(sandboxPath string, lesson types.Lesson) error {
+	stopProcesses(sandboxPath)
+
+	labsMu.Lock()
+	entry := labs[sandboxPath]
+	if entry != nil {
+		entry.manifest.Processes = nil
+	}
+	labsMu.Unlock()
+
+	if entry != nil {
+		if err := writeManifest(entry.manifest); err != nil {
+			return err
+		}
+	}
+	return startProcesses(sandboxPath, lesson)
+}
+
+func findHelper(sandboxPath, name string) *helperProcess {
+	labsMu.Lock()
+	defer labsMu.Unlock()
+	entry := labs[sandboxPath]
+	if entry == nil {
+		return nil
+	}
+	for _, helper := range entry.helpers {
+		if helper.name == name {
+			return helper
+		}
+	}
+	return nil
+}
+
+// ParseSignal accepts a signal as a number or a name with or without the
+// SIG prefix, such as "9", "KILL" or "SIGKILL".
+func ParseSignal(value string) (syscall.Signal, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	if n, err := strconv.Atoi(value); err == nil && n > 0 {
+		return syscall.Signal(n), nil
+	}
+	if sig, ok := signalNames[strings.TrimPrefix(value, "SIG")]; ok {
+		return sig, nil
+	}
+	return 0, fmt.Errorf("unknown signal %q", value)
+}
+
+// validateProcessSignaled checks that the named helper was terminated by a
+// signal, and by the expected one when given. The learner may have only just
+// sent it, so allow a moment for the exit to be reaped.
+func validateProcessSignaled(name, expected, sandboxPath string) bool {
+	helper := findHelper(sandboxPath, name)
+	if helper == nil {
+		return false
+	}
+
+	select {
+	case <-helper.done:
+	case <-time.After(250 * time.Millisecond):
+		return false
+	}
+
+	if helper.signal == 0 {
+		return false
+	}
+	if expected == "" {
+		return true
+	}
+	sig, err := ParseSignal(expected)
+	return err == nil && helper.signal == sig
+}
+
+func validateProcessRunning(name, sandboxPath string) bool {
+	helper := findHelper(sandboxPath, name)
+	return helper != nil && !helper.exited()
+}
@@ -0,0 +1,43 @@
+//go:build !unix
+
+package lab
+
+import (
+	"os"
+	"syscall"
+)
+
+var signalNames = map[string]syscall.Signal{
+	"HUP":  syscall.SIGHUP,
+	"INT":  syscall.SIGINT,
+	"QUIT": syscall.SIGQUIT,
+	"KILL": syscall.SIGKILL,
+	"TERM": syscall.SIGTERM,
+}
+
+// helperProcAttr is nil, as process groups are only available on Unix.
+func helperProcAttr() *syscall.SysProcAttr {
+	return nil
+}
+
+// sessionProcAttr is nil, as sessions are only available on Unix.
+func sessionProcAttr() *syscall.SysProcAttr {
+	return nil
+}
+
+// killProcessGroup can only kill pid itself where there are no process
+// groups.
+func killProcessGroup(pid int) {
+	if proc, err := os.FindProcess(pid); err == nil {
+		proc.Kill()
+	}
+}
+
+func processExists(pid int) bool {
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	proc.Release()
+	return true
+}
@@ -0,0 +1,37 @@
+//go:build unix
+
+package lab
+
+import (
+	"syscall"
+)
+
+var signalNames = map[string]syscall.Signal{
+	"HUP":  syscall.SIGHUP,
+	"INT":  syscall.SIGINT,
+	"QUIT": syscall.SIGQUIT,
+	"KILL": syscall.SIGKILL,
+	"USR1": syscall.SIGUSR1,
+	"USR2": syscall.SIGUSR2,
+	"TERM": syscall.SIGTERM,
+}
+
+// helperProcAttr starts a helper as the leader of its own process group.
+func helperProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setpgid: true}
+}
+
+// sessionProcAttr starts a process in a new session, away from the
+// controlling terminal, leading its own process group.
+func sessionProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true}
+}
+
+// killProcessGroup kills the process group led by pid.
+func killProcessGroup(pid int) {
+	syscall.Kill(-pid, syscall.SIGKILL)
+}
+
+func processExists(pid int) bool {
+	return syscall.Kill(pid, 0) == nil
+}
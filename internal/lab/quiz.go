@@ -0,0 +1,158 @@
+package lab
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/bobparsons/rootcamp/internal/types"
+)
+
+// UsesSandbox reports whether the lesson is worked in a lab. Quiz lessons
+// and lessons marked skipSandbox are answered without one.
+func UsesSandbox(lesson types.Lesson) bool {
+	return !lesson.SkipSandbox && lesson.Quiz == nil
+}
+
+// QuizItemID returns the item's ID, or its position counting from 1 if it
+// has none.
+func QuizItemID(quiz types.Quiz, item int) string {
+	if id := quiz.Items[item].ID; id != "" {
+		return id
+	}
+	return strconv.Itoa(item + 1)
+}
+
+// QuizPassScore is how many items must be answered correctly to pass.
+func QuizPassScore(quiz types.Quiz) int {
+	if quiz.PassScore > 0 && quiz.PassScore < len(quiz.Items) {
+		return quiz.PassScore
+	}
+	return len(quiz.Items)
+}
+
+// CheckQuizItem reports whether the answers are right. Choice items are
+// answered with the options picked, which must be exactly the correct ones;
+// other items with the single answer given.
+func CheckQuizItem(item types.QuizItem, answers []string) bool {
+	if item.Type == types.QuizMultipleChoice {
+		picked := slices.Clone(answers)
+		want := slices.Clone(item.Answers)
+		slices.Sort(picked)
+		slices.Sort(want)
+		return slices.Equal(slices.Compact(picked), slices.Compact(want))
+	}
+
+	if len(answers) != 1 {
+		return false
+	}
+	answer := normalizeAnswer(answers[0])
+	for _, accepted := range item.Answers {
+		if answer == normalizeAnswer(accepted) {
+			return true
+		}
+		// The whole command, with the blank filled in, is accepted too.
+		if item.Type == types.QuizComplete && answer == normalizeAnswer(strings.Replace(item.Command, types.QuizBlank, accepted, 1)) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeAnswer ignores leading, trailing and repeated whitespace, so
+// "ls  -l" and "ls -l" are the same answer.
+func normalizeAnswer(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// GradeQuiz checks every item against the learner's answers, given in item
+// order, and reports whether the quiz is passed.
+func GradeQuiz(quiz types.Quiz, answers [][]string) ([]types.QuizResult, bool) {
+	results := make([]types.QuizResult, len(quiz.Items))
+	score := 0
+	for i, item := range quiz.Items {
+		var given []string
+		if i < len(answers) {
+			given = answers[i]
+		}
+		correct := CheckQuizItem(item, given)
+		if correct {
+			score++
+		}
+		results[i] = types.QuizResult{
+			ItemID:  QuizItemID(quiz, i),
+			Answer:  strings.Join(given, "\n"),
+			Correct: correct,
+		}
+	}
+	return results, score >= QuizPassScore(quiz)
+}
+
+// CheckQuiz reports problems with the lesson's quiz that would make an item
+// impossible to answer, joined into one error.
+func CheckQuiz(lesson types.Lesson) error {
+	if lesson.Quiz == nil {
+		return nil
+	}
+	quiz := *lesson.Quiz
+
+	var errs []error
+	if len(quiz.Items) == 0 {
+		errs = append(errs, fmt.Errorf("quiz has no items"))
+	}
+	if quiz.PassScore > len(quiz.Items) {
+		errs = append(errs, fmt.Errorf("quiz pass score %d is more than its %d items", quiz.PassScore, len(quiz.Items)))
+	}
+
+	ids := make(map[string]bool)
+	for i, item := range quiz.Items {
+		id := QuizItemID(quiz, i)
+		if ids[id] {
+			errs = append(errs, fmt.Errorf("duplicate quiz item id %q", id))
+		}
+		ids[id] = true
+
+		if item.Question == "" {
+			errs = append(errs, fmt.Errorf("quiz item %s has no question", id))
+		}
+		if len(item.Answers) == 0 {
+			errs = append(errs, fmt.Errorf("quiz item %s has no answers", id))
+		}
+
+		choice := len(item.Options) > 0
+		switch item.Type {
+		case types.QuizSingleChoice, types.QuizMultipleChoice:
+			if !choice {
+				errs = append(errs, fmt.Errorf("quiz item %s has no options", id))
+			}
+			if item.Type == types.QuizSingleChoice && len(item.Answers) > 1 {
+				errs = append(errs, fmt.Errorf("quiz item %s is single choice but has %d answers", id, len(item.Answers)))
+			}
+		case types.QuizOutput:
+			if item.Command == "" {
+				errs = append(errs, fmt.Errorf("quiz item %s has no command", id))
+			}
+		case types.QuizComplete:
+			if strings.Count(item.Command, types.QuizBlank) != 1 {
+				errs = append(errs, fmt.Errorf("quiz item %s command must contain one %s blank", id, types.QuizBlank))
+			}
+			if choice {
+				errs = append(errs, fmt.Errorf("quiz item %s is typed and cannot have options", id))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("quiz item %s has unknown type %q", id, item.Type))
+			continue
+		}
+
+		if choice {
+			for _, answer := range item.Answers {
+				if !slices.Contains(item.Options, answer) {
+					errs = append(errs, fmt.Errorf("quiz item %s answer %q is not one of its options", id, answer))
+				}
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,313 @@
+package lab
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bobparsons/rootcamp/internal/types"
+)
+
+// Manifest describes a sandbox for anyone who finds it on disk, in
+// particular a later RootCamp run looking for sandboxes left behind by a
+// crash. It lives in the sandbox's state directory next to a lock file that
+// the owning process holds for as long as the sandbox exists.
+type Manifest struct {
+	SandboxPath string            `json:"sandbox"`
+	LessonID    string            `json:"lesson"`
+	OwnerPID    int               `json:"pid"`
+	CreatedAt   time.Time         `json:"createdAt"`
+	Processes   []ManifestProcess `json:"processes,omitempty"`
+}
+
+type ManifestProcess struct {
+	Name string `json:"name"`
+	PID  int    `json:"pid"`
+}
+
+type labEntry struct {
+	lock     *os.File
+	manifest Manifest
+	helpers  []*helperProcess
+}
+
+var (
+	labsMu sync.Mutex
+	labs   = map[string]*labEntry{}
+)
+
+// legacyRoot is where sandboxes were created before the root could be
+// configured.
+var legacyRoot = DefaultRoot
+
+const (
+	idAlphabet = "abcdefghijklmnopqrstuvwxyz0123456789"
+	idLength   = 10
+
+	// Sandboxes from before manifests existed had shorter IDs.
+	legacyIDLength = 5
+
+	// A state directory without a lock may belong to a sandbox that is still
+	// being registered by another RootCamp process.
+	registerGrace = time.Minute
+)
+
+func newSandboxID() (string, error) {
+	buf := make([]byte, idLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate sandbox id: %w", err)
+	}
+	for i, b := range buf {
+		buf[i] = idAlphabet[int(b)%len(idAlphabet)]
+	}
+	return string(buf), nil
+}
+
+func manifestPath(sandboxPath string) string {
+	return filepath.Join(StateDir(sandboxPath), "manifest.json")
+}
+
+func lockPath(sandboxPath string) string {
+	return filepath.Join(StateDir(sandboxPath), "lock")
+}
+
+// register claims a fresh sandbox path by creating its state directory,
+// which fails if the id is already taken, then locks it and writes the
+// manifest.
+func register(lesson types.Lesson) (string, error) {
+	var sandboxPath string
+	for attempt := 0; ; attempt++ {
+		id, err := newSandboxID()
+		if err != nil {
+			return "", err
+		}
+		sandboxPath = sandboxPrefix() + id
+		err = os.Mkdir(StateDir(sandboxPath), 0700)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, os.ErrExist) || attempt >= 10 {
+			return "", fmt.Errorf("failed to create sandbox state directory: %w", err)
+		}
+	}
+
+	lock, err := os.OpenFile(lockPath(sandboxPath), os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		os.RemoveAll(StateDir(sandboxPath))
+		return "", fmt.Errorf("failed to create sandbox lock: %w", err)
+	}
+	if err := tryLock(lock); err != nil {
+		lock.Close()
+		os.RemoveAll(StateDir(sandboxPath))
+		return "", fmt.Errorf("failed to lock sandbox: %w", err)
+	}
+
+	entry := &labEntry{
+		lock: lock,
+		manifest: Manifest{
+			SandboxPath: sandboxPath,
+			LessonID:    lesson.ID,
+			OwnerPID:    os.Getpid(),
+			CreatedAt:   time.Now(),
+		},
+	}
+
+	labsMu.Lock()
+	labs[sandboxPath] = entry
+	labsMu.Unlock()
+
+	if err := writeManifest(entry.manifest); err != nil {
+		unregister(sandboxPath)
+		os.RemoveAll(StateDir(sandboxPath))
+		return "", err
+	}
+
+	return sandboxPath, nil
+}
+
+func unregister(sandboxPath string) {
+	labsMu.Lock()
+	entry := labs[sandboxPath]
+	delete(labs, sandboxPath)
+	labsMu.Unlock()
+
+	if entry != nil && entry.lock != nil {
+		entry.lock.Close()
+	}
+}
+
+func writeManifest(manifest Manifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode sandbox manifest: %w", err)
+	}
+
+	path := manifestPath(manifest.SandboxPath)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write sandbox manifest: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write sandbox manifest: %w", err)
+	}
+	return nil
+}
+
+func readManifest(sandboxPath string) (*Manifest, error) {
+	data, err := os.ReadFile(manifestPath(sandboxPath))
+	if err != nil {
+		return nil, err
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse sandbox manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+// CleanupAll removes every sandbox this process still owns. It is meant for
+// shutdown, so a lesson left open when the program exits does not leave its
+// sandbox and helper processes behind.
+func CleanupAll() {
+	labsMu.Lock()
+	var paths []string
+	for path := range labs {
+		paths = append(paths, path)
+	}
+	labsMu.Unlock()
+
+	for _, path := range paths {
+		Cleanup(path)
+	}
+}
+
+// Reap removes sandboxes in the current root whose owning RootCamp process
+// is gone, killing any helper processes they left running. A sandbox is
+// abandoned when nobody holds its lock. It returns the number of sandboxes
+// removed, along with any directories in the old default root named like the
+// sandboxes from before manifests existed. Nothing proves those were made by
+// RootCamp, so they are left for the learner to remove.
+func Reap() (int, []string, error) {
+	matches, err := filepath.Glob(sandboxPrefix() + "*")
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to list sandboxes: %w", err)
+	}
+
+	seen := map[string]bool{}
+	reaped := 0
+	var leftover []string
+	for _, match := range matches {
+		sandboxPath := strings.TrimSuffix(strings.TrimSuffix(match, ".state"), ".tmp")
+		if seen[sandboxPath] {
+			continue
+		}
+		seen[sandboxPath] = true
+
+		if isLegacySandbox(sandboxPath) {
+			leftover = append(leftover, sandboxPath)
+			continue
+		}
+
+		labsMu.Lock()
+		_, ours := labs[sandboxPath]
+		labsMu.Unlock()
+		if ours {
+			continue
+		}
+
+		if reapSandbox(sandboxPath) {
+			reaped++
+		}
+	}
+
+	return reaped, leftover, nil
+}
+
+// reapSandbox removes a sandbox with a state directory that nobody holds the
+// lock of. The path is checked before anything is deleted, and the manifest
+// must describe the same sandbox for its processes to be killed.
+func reapSandbox(sandboxPath string) bool {
+	if !isSandboxPath(sandboxPath) || filepath.Dir(sandboxPath) != Root() {
+		return false
+	}
+	stateInfo, err := os.Stat(StateDir(sandboxPath))
+	if err != nil {
+		return false
+	}
+
+	lock, err := os.OpenFile(lockPath(sandboxPath), os.O_RDWR, 0600)
+	if err != nil {
+		if time.Since(stateInfo.ModTime()) < registerGrace {
+			return false
+		}
+	} else {
+		defer lock.Close()
+		if err := tryLock(lock); err != nil {
+			return false
+		}
+	}
+
+	if manifest, err := readManifest(sandboxPath); err == nil && manifest.SandboxPath == sandboxPath {
+		for _, proc := range manifest.Processes {
+			if ownsProcess(proc.PID, sandboxPath) {
+				killProcessGroup(proc.PID)
+			}
+		}
+	}
+
+	return removeSandbox(sandboxPath) == nil
+}
+
+// isLegacySandbox reports a directory in the root old versions always used,
+// named the way their sandboxes were, with no state directory beside it.
+func isLegacySandbox(sandboxPath string) bool {
+	if filepath.Dir(sandboxPath) != legacyRoot || !isLegacySandboxName(filepath.Base(sandboxPath)) {
+		return false
+	}
+	if _, err := os.Lstat(StateDir(sandboxPath)); !os.IsNotExist(err) {
+		return false
+	}
+	info, err := os.Lstat(sandboxPath)
+	return err == nil && info.IsDir()
+}
+
+func isLegacySandboxName(name string) bool {
+	id, ok := strings.CutPrefix(name, sandboxNamePrefix)
+	if !ok || len(id) != legacyIDLength {
+		return false
+	}
+	for _, r := range id {
+		if !strings.ContainsRune(idAlphabet, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// ownsProcess guards against PID reuse by checking that the process still
+// carries the sandbox in its environment. Where /proc is not available the
+// manifest is trusted.
+func ownsProcess(pid int, sandboxPath string) bool {
+	if pid <= 0 {
+		return false
+	}
+	environ, err := os.ReadFile(fmt.Sprintf("/proc/%d/environ", pid))
+	if err != nil {
+		if _, statErr := os.Stat("/proc/self"); statErr == nil {
+			return false
+		}
+		return processExists(pid)
+	}
+	for _, entry := range strings.Split(string(environ), "\x00") {
+		if entry == "ROOTCAMP_SANDBOX="+sandboxPath {
+			return true
+		}
+	}
+	return false
+}
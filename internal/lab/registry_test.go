@@ -0,0 +1,77 @@
+package lab
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func useTestRoot(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	previous := Root()
+	if err := SetRoot(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { SetRoot(previous) })
+	return dir
+}
+
+func TestReapLeavesLegacySandboxes(t *testing.T) {
+	dir := useTestRoot(t)
+	defer func(previous string) { legacyRoot = previous }(legacyRoot)
+	legacyRoot = dir
+
+	legacy := filepath.Join(dir, "rootcamp-ab12z")
+	others := []string{
+		filepath.Join(dir, "rootcamp-notes"),
+		filepath.Join(dir, "rootcamp-test1"),
+		filepath.Join(dir, "rootcamp-notes-2024"),
+	}
+	for _, p := range append([]string{legacy}, others...) {
+		if err := os.Mkdir(p, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, leftover, err := Reap()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range append([]string{legacy}, others...) {
+		if _, err := os.Stat(p); err != nil {
+			t.Errorf("%s was reaped", p)
+		}
+	}
+	want := []string{legacy, others[0], others[1]}
+	if !slices.Equal(leftover, want) {
+		t.Errorf("leftover = %v, want %v", leftover, want)
+	}
+}
+
+// A stray state directory next to a directory that isn't named like a sandbox
+// must not get that directory deleted.
+func TestReapSkipsInvalidSandboxPaths(t *testing.T) {
+	dir := useTestRoot(t)
+
+	notes := filepath.Join(dir, "rootcamp-notes")
+	for _, p := range []string{notes, notes + ".state"} {
+		if err := os.Mkdir(p, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-2 * registerGrace)
+	if err := os.Chtimes(notes+".state", old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := Reap(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(notes); err != nil {
+		t.Errorf("%s was reaped", notes)
+	}
+}
@@ -0,0 +1,171 @@
+package lab
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// DefaultRoot is where sandboxes are created unless a different root is
+// configured.
+const DefaultRoot = "/tmp"
+
+const (
+	sandboxNamePrefix = "rootcamp-"
+
+	// RootEnv overrides the sandbox root from the environment.
+	RootEnv = "ROOTCAMP_SANDBOX_ROOT"
+)
+
+var (
+	rootMu sync.RWMutex
+	root   = DefaultRoot
+)
+
+// Root returns the directory sandboxes are created in.
+func Root() string {
+	rootMu.RLock()
+	defer rootMu.RUnlock()
+	return root
+}
+
+// SetRoot changes the directory new sandboxes are created in after checking
+// that it is safe to use. Sandboxes already created keep their paths.
+func SetRoot(dir string) error {
+	cleaned, err := CheckRoot(dir)
+	if err != nil {
+		return err
+	}
+	rootMu.Lock()
+	root = cleaned
+	rootMu.Unlock()
+	return nil
+}
+
+// CheckRoot validates a sandbox root and returns its cleaned absolute path.
+// The directory is created if missing. It must be writable, and if other
+// users can write to it the sticky bit must be set, as on /tmp, so that
+// nobody else can replace a sandbox while it is in use.
+func CheckRoot(dir string) (string, error) {
+	dir, err := ValidateRoot(dir)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create sandbox root: %w", err)
+	}
+	if err := checkRootDir(dir); err != nil {
+		return "", err
+	}
+
+	probe, err := os.CreateTemp(dir, ".rootcamp-probe-")
+	if err != nil {
+		return "", fmt.Errorf("sandbox root %s is not writable: %w", dir, err)
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+
+	return dir, nil
+}
+
+// ValidateRoot checks a sandbox root without touching the disk, for
+// validating a path as it is typed, and returns its cleaned absolute path.
+// A root that exists gets the same checks as in CheckRoot; one that doesn't
+// must have a writable directory as its nearest existing parent, so that
+// CheckRoot can create it.
+func ValidateRoot(dir string) (string, error) {
+	dir = strings.TrimSpace(dir)
+	if dir == "" {
+		return "", fmt.Errorf("sandbox root is empty")
+	}
+	if strings.HasPrefix(dir, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to expand %s: %w", dir, err)
+		}
+		dir = filepath.Join(home, dir[2:])
+	}
+	if !filepath.IsAbs(dir) {
+		return "", fmt.Errorf("sandbox root %s is not an absolute path", dir)
+	}
+	dir = filepath.Clean(dir)
+	if dir == "/" {
+		return "", fmt.Errorf("sandbox root cannot be /")
+	}
+
+	if _, err := os.Stat(dir); err == nil {
+		if err := checkRootDir(dir); err != nil {
+			return "", err
+		}
+		if !writable(dir) {
+			return "", fmt.Errorf("sandbox root %s is not writable", dir)
+		}
+		return dir, nil
+	}
+
+	parent := filepath.Dir(dir)
+	for {
+		info, err := os.Stat(parent)
+		if err == nil {
+			if !info.IsDir() {
+				return "", fmt.Errorf("cannot create sandbox root %s: %s is not a directory", dir, parent)
+			}
+			if !writable(parent) {
+				return "", fmt.Errorf("cannot create sandbox root %s: %s is not writable", dir, parent)
+			}
+			return dir, nil
+		}
+		if !os.IsNotExist(err) || filepath.Dir(parent) == parent {
+			return "", fmt.Errorf("cannot create sandbox root %s: %w", dir, err)
+		}
+		parent = filepath.Dir(parent)
+	}
+}
+
+// checkRootDir checks an existing sandbox root is a directory that nobody
+// else can replace sandboxes in.
+func checkRootDir(dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read sandbox root: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("sandbox root %s is not a directory", dir)
+	}
+	if info.Mode().Perm()&0o002 != 0 && info.Mode()&os.ModeSticky == 0 {
+		return fmt.Errorf("sandbox root %s is world-writable without the sticky bit", dir)
+	}
+	if ownedByOtherUser(info) {
+		return fmt.Errorf("sandbox root %s is owned by another user", dir)
+	}
+	return nil
+}
+
+func sandboxPrefix() string {
+	return filepath.Join(Root(), sandboxNamePrefix)
+}
+
+// isSandboxPath reports whether a path names a sandbox directly inside a
+// sandbox root. Cleanup refuses anything else so that a bad path can never
+// turn into a recursive delete elsewhere.
+func isSandboxPath(sandboxPath string) bool {
+	if sandboxPath == "" || !filepath.IsAbs(sandboxPath) || filepath.Clean(sandboxPath) != sandboxPath {
+		return false
+	}
+	if filepath.Dir(sandboxPath) == "/" {
+		return false
+	}
+	id := strings.TrimPrefix(filepath.Base(sandboxPath), sandboxNamePrefix)
+	if id == filepath.Base(sandboxPath) || len(id) != idLength {
+		return false
+	}
+	for _, r := range id {
+		if !strings.ContainsRune(idAlphabet, r) {
+			return false
+		}
+	}
+	return true
+}
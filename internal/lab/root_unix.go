@@ -0,0 +1,23 @@
+//go:build unix
+
+package lab
+
+import (
+	"os"
+	"syscall"
+)
+
+// ownedByOtherUser reports whether the file belongs to someone other than
+// the current user or root.
+func ownedByOtherUser(info os.FileInfo) bool {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	return ok && int(stat.Uid) != os.Getuid() && stat.Uid != 0
+}
+
+// writable reports whether the current user may create files in dir.
+func writable(dir string) bool {
+	return syscall.Access(dir, wOK) == nil
+}
+
+// wOK is W_OK from unistd.h, which the syscall package does not define.
+const wOK = 0x2
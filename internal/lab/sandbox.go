@@ -1,87 +1,346 @@
 package lab
 
 import (
+	"errors"
 	"fmt"
-	"math/rand"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/bobparsons/rootcamp/internal/types"
 )
 
-func generateShortID() string {
-	const charset = "abcdefghijklmnopqrstuvwxyz0123456789"
-	const length = 5
+// Create builds the lesson's sandbox. The tree is populated in a staging
+// directory and renamed into place, so a sandbox path either holds the
+// complete tree or does not exist. The sandbox is registered with a manifest
+// and lock before anything is written, which lets Reap find it if RootCamp
+// dies before Cleanup runs.
+func Create(lesson types.Lesson) (string, error) {
+	sandboxPath, err := register(lesson)
+	if err != nil {
+		return "", err
+	}
+
+	staging := stagingPath(sandboxPath)
+	if err := populate(staging, lesson); err != nil {
+		Cleanup(sandboxPath)
+		return "", err
+	}
+
+	if err := takeSnapshot(staging, sandboxPath); err != nil {
+		Cleanup(sandboxPath)
+		return "", err
+	}
 
-	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
-	id := make([]byte, length)
-	for i := range id {
-		id[i] = charset[rng.Intn(len(charset))]
+	if err := os.Rename(staging, sandboxPath); err != nil {
+		Cleanup(sandboxPath)
+		return "", fmt.Errorf("failed to move sandbox into place: %w", err)
 	}
-	return string(id)
+
+	if err := writeShellHooks(sandboxPath); err != nil {
+		Cleanup(sandboxPath)
+		return "", err
+	}
+
+	if err := startProcesses(sandboxPath, lesson); err != nil {
+		Cleanup(sandboxPath)
+		return "", err
+	}
+
+	return sandboxPath, nil
 }
 
-func Create(lesson types.Lesson) (string, error) {
-	sandboxID := generateShortID()
-	sandboxPath := filepath.Join("/tmp", fmt.Sprintf("rootcamp-%s", sandboxID))
+func stagingPath(sandboxPath string) string {
+	return sandboxPath + ".tmp"
+}
 
-	if err := os.MkdirAll(sandboxPath, 0755); err != nil {
-		return "", fmt.Errorf("failed to create sandbox: %w", err)
+func populate(root string, lesson types.Lesson) error {
+	if err := os.Mkdir(root, 0755); err != nil {
+		return fmt.Errorf("failed to create sandbox: %w", err)
 	}
 
 	for _, dir := range lesson.Sandbox.Dirs {
-		dirPath := filepath.Join(sandboxPath, dir)
+		dirPath := sandboxJoin(root, dir)
 		if err := os.MkdirAll(dirPath, 0755); err != nil {
-			Cleanup(sandboxPath)
-			return "", fmt.Errorf("failed to create directory %s: %w", dir, err)
+			return fmt.Errorf("failed to create directory %s: %w", dir, err)
 		}
 	}
 
 	for filePath, content := range lesson.Sandbox.Files {
-		fullPath := filepath.Join(sandboxPath, filePath)
-
-		dirPath := filepath.Dir(fullPath)
-		if err := os.MkdirAll(dirPath, 0755); err != nil {
-			Cleanup(sandboxPath)
-			return "", fmt.Errorf("failed to create parent directory: %w", err)
+		if err := writeSandboxFile(root, filePath, content, 0644); err != nil {
+			return err
 		}
+	}
 
-		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
-			Cleanup(sandboxPath)
-			return "", fmt.Errorf("failed to create file %s: %w", filePath, err)
+	for filePath, content := range lesson.Sandbox.Scripts {
+		if err := writeSandboxFile(root, filePath, content, 0755); err != nil {
+			return err
 		}
 	}
 
+	if err := writeFixtures(root, lesson.Sandbox.Fixtures); err != nil {
+		return err
+	}
+
+	if err := writeGenerated(root, lesson.Sandbox.Generated); err != nil {
+		return err
+	}
+
 	for linkName, target := range lesson.Sandbox.Symlinks {
-		linkPath := filepath.Join(sandboxPath, linkName)
+		linkPath := sandboxJoin(root, linkName)
 
 		dirPath := filepath.Dir(linkPath)
 		if err := os.MkdirAll(dirPath, 0755); err != nil {
-			Cleanup(sandboxPath)
-			return "", fmt.Errorf("failed to create parent directory for symlink: %w", err)
+			return fmt.Errorf("failed to create parent directory for symlink: %w", err)
 		}
 
 		if err := os.Symlink(target, linkPath); err != nil {
-			Cleanup(sandboxPath)
-			return "", fmt.Errorf("failed to create symlink %s: %w", linkName, err)
+			return fmt.Errorf("failed to create symlink %s: %w", linkName, err)
 		}
 	}
 
-	return sandboxPath, nil
+	if err := applyMtimes(root, lesson.Sandbox.Mtimes); err != nil {
+		return err
+	}
+
+	return applyModes(root, lesson.Sandbox.Modes)
+}
+
+func writeSandboxFile(sandboxPath, filePath, content string, perm os.FileMode) error {
+	fullPath := sandboxJoin(sandboxPath, filePath)
+
+	dirPath := filepath.Dir(fullPath)
+	if err := os.MkdirAll(dirPath, 0755); err != nil {
+		return fmt.Errorf("failed to create parent directory: %w", err)
+	}
+
+	if err := os.WriteFile(fullPath, []byte(content), perm); err != nil {
+		return fmt.Errorf("failed to create file %s: %w", filePath, err)
+	}
+
+	// WriteFile is subject to the umask, so set the mode explicitly.
+	if err := os.Chmod(fullPath, perm); err != nil {
+		return fmt.Errorf("failed to set mode on %s: %w", filePath, err)
+	}
+
+	return nil
+}
+
+// EscapesSandbox reports whether a sandbox path is absolute or climbs out of
+// the sandbox root.
+func EscapesSandbox(p string) bool {
+	if strings.HasPrefix(p, "/") || filepath.IsAbs(p) {
+		return true
+	}
+	clean := path.Clean(filepath.ToSlash(p))
+	return clean == ".." || strings.HasPrefix(clean, "../")
+}
+
+// CheckSandboxPaths reports every path in the lesson's sandbox that is
+// outside it, joined into one error. Lessons from packs are not trusted to
+// keep to their sandbox.
+func CheckSandboxPaths(lesson types.Lesson) error {
+	sb := lesson.Sandbox
+	var errs []error
+	check := func(kind, p string) {
+		if EscapesSandbox(p) {
+			errs = append(errs, fmt.Errorf("sandbox %s %s is outside the sandbox", kind, p))
+		}
+	}
+
+	check("startDir", sb.StartDir)
+	for _, dir := range sb.Dirs {
+		check("directory", dir)
+	}
+	for _, p := range sortedPaths(sb.Files) {
+		check("file", p)
+	}
+	for _, p := range sortedPaths(sb.Scripts) {
+		check("script", p)
+	}
+	for _, p := range sortedPaths(sb.Symlinks) {
+		check("symlink", p)
+	}
+	for _, fixture := range sb.Fixtures {
+		check("fixture", fixture.Path)
+	}
+	for _, gen := range sb.Generated {
+		check("generated file", gen.Path)
+	}
+	for _, p := range sortedPaths(sb.Modes) {
+		check("mode", p)
+	}
+	for _, p := range sortedPaths(sb.Mtimes) {
+		check("mtime", p)
+	}
+	return errors.Join(errs...)
+}
+
+func sortedPaths(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sandboxJoin joins a lesson's sandbox path to the root, treating it as
+// rooted there so that ".." and absolute paths cannot climb out.
+func sandboxJoin(root, rel string) string {
+	return filepath.Join(root, filepath.Clean("/"+rel))
+}
+
+// resolveInSandbox is sandboxJoin for paths that are changed after the
+// lesson's symlinks exist, which Chmod and Chtimes would follow. It refuses
+// a path that leads through a symlink to somewhere outside the sandbox.
+func resolveInSandbox(root, rel string) (string, error) {
+	fullPath := sandboxJoin(root, rel)
+	resolvedRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve sandbox: %w", err)
+	}
+	resolved, err := filepath.EvalSymlinks(fullPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", rel, err)
+	}
+	if resolved != resolvedRoot && !strings.HasPrefix(resolved, resolvedRoot+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside the sandbox", rel)
+	}
+	return fullPath, nil
+}
+
+// deepestFirst orders sandbox paths so that children come before their
+// parents. Setting a parent's mtime or locking down its mode last keeps
+// later changes to its children from undoing or failing on it.
+func deepestFirst(paths map[string]string) []string {
+	keys := make([]string, 0, len(paths))
+	for path := range paths {
+		keys = append(keys, path)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		di := strings.Count(filepath.Clean(keys[i]), "/")
+		dj := strings.Count(filepath.Clean(keys[j]), "/")
+		if di != dj {
+			return di > dj
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+func applyMtimes(sandboxPath string, mtimes map[string]string) error {
+	for _, path := range deepestFirst(mtimes) {
+		mtime, err := ParseSandboxTime(mtimes[path])
+		if err != nil {
+			return fmt.Errorf("invalid mtime for %s: %w", path, err)
+		}
+		fullPath, err := resolveInSandbox(sandboxPath, path)
+		if err != nil {
+			return err
+		}
+		if err := os.Chtimes(fullPath, mtime, mtime); err != nil {
+			return fmt.Errorf("failed to set mtime on %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+func applyModes(sandboxPath string, modes map[string]string) error {
+	for _, path := range deepestFirst(modes) {
+		mode, err := ParseMode(modes[path])
+		if err != nil {
+			return fmt.Errorf("invalid mode for %s: %w", path, err)
+		}
+		fullPath, err := resolveInSandbox(sandboxPath, path)
+		if err != nil {
+			return err
+		}
+		if err := os.Chmod(fullPath, mode); err != nil {
+			return fmt.Errorf("failed to set mode on %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// ParseMode reads an octal mode such as "0755" or "4755", including the
+// setuid, setgid and sticky bits.
+func ParseMode(value string) (os.FileMode, error) {
+	bits, err := strconv.ParseUint(strings.TrimSpace(value), 8, 32)
+	if err != nil || bits > 0o7777 {
+		return 0, fmt.Errorf("%q is not an octal mode", value)
+	}
+
+	mode := os.FileMode(bits & 0o777)
+	if bits&0o4000 != 0 {
+		mode |= os.ModeSetuid
+	}
+	if bits&0o2000 != 0 {
+		mode |= os.ModeSetgid
+	}
+	if bits&0o1000 != 0 {
+		mode |= os.ModeSticky
+	}
+	return mode, nil
+}
+
+// ParseSandboxTime accepts RFC 3339 timestamps as well as the shorter
+// "2006-01-02 15:04" and "2006-01-02" forms, read as local time.
+func ParseSandboxTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not a recognised time", value)
 }
 
 func GetStartPath(sandboxPath string, lesson types.Lesson) string {
 	if lesson.Sandbox.StartDir == "" {
 		return sandboxPath
 	}
-	return filepath.Join(sandboxPath, lesson.Sandbox.StartDir)
+	return sandboxJoin(sandboxPath, lesson.Sandbox.StartDir)
 }
 
 func Cleanup(sandboxPath string) error {
-	if sandboxPath == "" || !strings.HasPrefix(sandboxPath, "/tmp/rootcamp-") {
+	if !isSandboxPath(sandboxPath) {
 		return fmt.Errorf("invalid sandbox path: %s", sandboxPath)
 	}
-	return os.RemoveAll(sandboxPath)
+	stopProcesses(sandboxPath)
+	defer unregister(sandboxPath)
+	return removeSandbox(sandboxPath)
+}
+
+func removeSandbox(sandboxPath string) error {
+	for _, path := range []string{sandboxPath, stagingPath(sandboxPath)} {
+		makeRemovable(path)
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+	}
+	return os.RemoveAll(StateDir(sandboxPath))
+}
+
+// makeRemovable gives the owner full access to every directory in the tree,
+// since RemoveAll cannot empty a directory it is unable to read or write.
+// Lessons and learners both lock directories down.
+func makeRemovable(root string) {
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			os.Chmod(path, 0700)
+		}
+		return nil
+	})
 }
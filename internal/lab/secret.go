@@ -0,0 +1,146 @@
+package lab
+
+import (
+	"crypto/rand"
+	"strings"
+
+	"github.com/bobparsons/rootcamp/internal/types"
+)
+
+// SecretPlaceholder is replaced with a code generated for each attempt,
+// wherever it appears in the sandbox, such as in paths, file contents and
+// helper process scripts, and in requirements. Codes then cannot be read out of the lesson
+// files or passed from one learner to another.
+const SecretPlaceholder = "{SECRET_CODE}"
+
+// NewSecret returns a code such as "K7QM-2XPA-9HDT". Letters and digits that
+// are easily confused are left out.
+func NewSecret() string {
+	const charset = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	b := make([]byte, 12)
+	rand.Read(b)
+	for i := range b {
+		b[i] = charset[int(b[i])%len(charset)]
+	}
+	return string(b[:4]) + "-" + string(b[4:8]) + "-" + string(b[8:])
+}
+
+// ApplySecret returns a copy of the lesson with every secret placeholder
+// filled in. The lesson passed in is left untouched.
+func ApplySecret(lesson types.Lesson, secret string) types.Lesson {
+	fill := func(s string) string {
+		return strings.ReplaceAll(s, SecretPlaceholder, secret)
+	}
+
+	sb := &lesson.Sandbox
+	sb.StartDir = fill(sb.StartDir)
+	sb.Dirs = secretList(sb.Dirs, fill)
+	sb.Files = secretMap(sb.Files, fill)
+	sb.Scripts = secretMap(sb.Scripts, fill)
+	sb.Symlinks = secretMap(sb.Symlinks, fill)
+	sb.Modes = secretMap(sb.Modes, fill)
+	sb.Mtimes = secretMap(sb.Mtimes, fill)
+	sb.Fixtures = secretFixtures(sb.Fixtures, fill)
+
+	if sb.Generated != nil {
+		generated := make([]types.GeneratedFile, len(sb.Generated))
+		for i, g := range sb.Generated {
+			g.Path = fill(g.Path)
+			g.Columns = secretList(g.Columns, fill)
+			g.Delimiter = fill(g.Delimiter)
+			generated[i] = g
+		}
+		sb.Generated = generated
+	}
+
+	if sb.Processes != nil {
+		processes := make([]types.SandboxProcess, len(sb.Processes))
+		for i, p := range sb.Processes {
+			p.Name = fill(p.Name)
+			p.Script = fill(p.Script)
+			processes[i] = p
+		}
+		sb.Processes = processes
+	}
+
+	lesson.Requirements = secretRequirements(lesson.Requirements, fill)
+
+	if lesson.Steps != nil {
+		steps := make([]types.LessonStep, len(lesson.Steps))
+		for i, step := range lesson.Steps {
+			step.Requirements = secretRequirements(step.Requirements, fill)
+			steps[i] = step
+		}
+		lesson.Steps = steps
+	}
+
+	return lesson
+}
+
+func secretList(list []string, fill func(string) string) []string {
+	if list == nil {
+		return nil
+	}
+	out := make([]string, len(list))
+	for i, s := range list {
+		out[i] = fill(s)
+	}
+	return out
+}
+
+func secretMap(m map[string]string, fill func(string) string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		out[fill(k)] = fill(v)
+	}
+	return out
+}
+
+func secretRequirements(reqs []types.Requirement, fill func(string) string) []types.Requirement {
+	if reqs == nil {
+		return nil
+	}
+	out := make([]types.Requirement, len(reqs))
+	for i, req := range reqs {
+		req.Expected = fill(req.Expected)
+		req.Path = fill(req.Path)
+		req.Entries = secretList(req.Entries, fill)
+		req.Command = fill(req.Command)
+		req.Process = fill(req.Process)
+		req.Member = fill(req.Member)
+		if req.Compute != nil {
+			compute := *req.Compute
+			compute.Pattern = fill(compute.Pattern)
+			compute.Delimiter = fill(compute.Delimiter)
+			req.Compute = &compute
+		}
+		req.All = secretRequirements(req.All, fill)
+		req.Any = secretRequirements(req.Any, fill)
+		if req.Not != nil {
+			not := secretRequirements([]types.Requirement{*req.Not}, fill)[0]
+			req.Not = &not
+		}
+		out[i] = req
+	}
+	return out
+}
+
+func secretFixtures(fixtures []types.SandboxFixture, fill func(string) string) []types.SandboxFixture {
+	if fixtures == nil {
+		return nil
+	}
+	out := make([]types.SandboxFixture, len(fixtures))
+	for i, f := range fixtures {
+		f.Path = fill(f.Path)
+		f.Content = fill(f.Content)
+		f.Files = secretMap(f.Files, fill)
+		f.Dirs = secretList(f.Dirs, fill)
+		f.Modes = secretMap(f.Modes, fill)
+		f.Fixtures = secretFixtures(f.Fixtures, fill)
+		out[i] = f
+	}
+	return out
+}
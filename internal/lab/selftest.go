@@ -0,0 +1,103 @@
+package lab
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/bobparsons/rootcamp/internal/types"
+)
+
+// SolutionTimeout bounds how long a lesson's reference solution may run.
+const SolutionTimeout = 30 * time.Second
+
+// ErrNoSolution is returned by RunSolution for lessons that have no
+// reference solution, or no sandbox to run one in.
+var ErrNoSolution = errors.New("lesson has no solution")
+
+// SolutionResult is what a lesson's reference solution did: the answer it
+// gave, what it wrote to stderr and why the lesson still failed, if it did.
+type SolutionResult struct {
+	Answer   string
+	Stderr   string
+	Failures []string
+}
+
+// RunSolution checks a lesson against its reference solution. A fresh
+// sandbox is created with its own secret code, and the solution is fed line
+// by line to an interactive bash with the lab shell hooks, so the commands
+// land in the history exactly as a learner's would. The last line printed by
+// the solution's final command is taken as the learner's answer. The
+// learner's own rc files are not read, and HOME points into the sandbox's
+// state directory.
+func RunSolution(lesson types.Lesson) (SolutionResult, error) {
+	if lesson.Solution == "" || !UsesSandbox(lesson) {
+		return SolutionResult{}, ErrNoSolution
+	}
+
+	lesson = ApplySecret(lesson, NewSecret())
+	sandboxPath, err := Create(lesson)
+	if err != nil {
+		return SolutionResult{}, err
+	}
+	defer Cleanup(sandboxPath)
+
+	ctx, cancel := context.WithTimeout(context.Background(), SolutionTimeout)
+	defer cancel()
+
+	c := exec.CommandContext(ctx, "bash", "--rcfile", StateDir(sandboxPath)+"/bashrc", "-i")
+	c.Dir = GetStartPath(sandboxPath, lesson)
+	// Each prompt marks the end of a command's output, which is how the
+	// final command's output is picked out.
+	c.Env = append(labEnv(sandboxPath, lesson),
+		"HOME="+StateDir(sandboxPath),
+		"PWD="+c.Dir,
+		"PS1=", "PS2=",
+		`PROMPT_COMMAND=printf '\036'`,
+	)
+	c.Stdin = strings.NewReader(strings.TrimSuffix(lesson.Solution, "\n") + "\nexit\n")
+	var stdout, stderr bytes.Buffer
+	c.Stdout = &stdout
+	c.Stderr = &stderr
+	c.WaitDelay = time.Second
+	// Keep bash away from the terminal selftest was started from, so job
+	// control in a solution cannot take it over.
+	c.SysProcAttr = sessionProcAttr()
+
+	// A non-zero exit is fine, since solutions may show a command failing;
+	// only the state they leave behind is graded.
+	runErr := c.Run()
+	if c.Process != nil {
+		// Sweep up anything the solution left running in the background.
+		killProcessGroup(c.Process.Pid)
+	}
+	if ctx.Err() != nil {
+		return SolutionResult{}, fmt.Errorf("solution did not finish within %s", SolutionTimeout)
+	}
+	var exitErr *exec.ExitError
+	if runErr != nil && !errors.As(runErr, &exitErr) && !errors.Is(runErr, exec.ErrWaitDelay) {
+		return SolutionResult{}, fmt.Errorf("failed to run solution: %w", runErr)
+	}
+
+	result := SolutionResult{
+		Answer: finalAnswer(stdout.String()),
+		Stderr: strings.TrimSpace(stderr.String()),
+	}
+	result.Failures = LessonFailures(lesson, result.Answer, sandboxPath)
+	return result, nil
+}
+
+// finalAnswer returns the last line of output from the last command before
+// the shell exited.
+func finalAnswer(output string) string {
+	outputs := strings.Split(output, "\036")
+	if len(outputs) < 2 {
+		return ""
+	}
+	lines := strings.Split(strings.TrimSpace(outputs[len(outputs)-2]), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
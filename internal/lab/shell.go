@@ -0,0 +1,171 @@
+package lab
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/bobparsons/rootcamp/internal/types"
+)
+
+// Keys that act on the lab rather than going to its shell, as Bubble Tea
+// names them and as they are shown to the learner. ESC and Ctrl+D belong to
+// the programs run in the lab, such as vim and cat, so the lab uses keys
+// that shells and their programs leave alone.
+const (
+	CheckKey      = "f2"
+	CheckKeyLabel = "F2"
+	LeaveKey      = "ctrl+]"
+	LeaveKeyLabel = "Ctrl+]"
+)
+
+const shellGreeting = `printf '\033[1;92mROOT CAMP - LAB SESSION\033[0m  \033[1;97m%s\033[0m\n' "$ROOTCAMP_LESSON"
+printf '\033[36mSandbox: %s\033[0m\n' "$ROOTCAMP_SANDBOX"
+[ -n "$ROOTCAMP_ISOLATED" ] && printf '\033[36mIsolated: you are root here, but only the sandbox is writable\033[0m\n'
+printf '\033[35mPress \033[1m` + CheckKeyLabel + `\033[0m\033[35m to check your work or give your answer, or type \033[1;91mexit\033[0m\033[35m when you'"'"'re done.\033[0m\n\n'
+cd "$ROOTCAMP_START"
+`
+
+// The hooks below append one tab separated line per command to
+// $ROOTCAMP_HISTORY: unix time, exit status, working directory and the
+// command itself. They run before any prompt hooks from the learner's own
+// rc files so $? still belongs to the command.
+const bashHook = `if [ -f "$HOME/.bashrc" ]; then
+	. "$HOME/.bashrc"
+fi
+
+HISTFILE="$ROOTCAMP_STATE/bash_history"
+HISTCONTROL=
+HISTIGNORE=
+__rootcamp_histre='^ *([0-9]+)\*? *(.*)$'
+__rootcamp_histnum=0
+
+__rootcamp_log() {
+	local status=$? entry cmd
+	entry=$(HISTTIMEFORMAT= builtin history 1)
+	if [[ $entry =~ $__rootcamp_histre ]]; then
+		if [[ ${BASH_REMATCH[1]} != "$__rootcamp_histnum" ]]; then
+			__rootcamp_histnum=${BASH_REMATCH[1]}
+			cmd=${BASH_REMATCH[2]}
+			cmd=${cmd//$'\t'/ }
+			cmd=${cmd//$'\n'/ }
+			printf '%s\t%s\t%s\t%s\n' "$(date +%s)" "$status" "$PWD" "$cmd" >> "$ROOTCAMP_HISTORY"
+		fi
+	fi
+	return $status
+}
+
+PROMPT_COMMAND="__rootcamp_log${PROMPT_COMMAND:+; $PROMPT_COMMAND}"
+`
+
+const zshEnvHook = `__rootcamp_zdotdir=$ZDOTDIR
+ZDOTDIR=${ROOTCAMP_ZDOTDIR:-$HOME}
+[[ -f $ZDOTDIR/.zshenv ]] && source $ZDOTDIR/.zshenv
+ZDOTDIR=$__rootcamp_zdotdir
+`
+
+const zshRCHook = `ZDOTDIR=${ROOTCAMP_ZDOTDIR:-$HOME}
+[[ -f $ZDOTDIR/.zshrc ]] && source $ZDOTDIR/.zshrc
+
+__rootcamp_cmd=
+__rootcamp_preexec() {
+	__rootcamp_cmd=$1
+}
+__rootcamp_precmd() {
+	local st=$? cmd
+	if [[ -n $__rootcamp_cmd ]]; then
+		cmd=${__rootcamp_cmd//$'\t'/ }
+		cmd=${cmd//$'\n'/ }
+		printf '%s\t%s\t%s\t%s\n' "$(date +%s)" "$st" "$PWD" "$cmd" >> "$ROOTCAMP_HISTORY"
+		__rootcamp_cmd=
+	fi
+	return $st
+}
+preexec_functions=(__rootcamp_preexec $preexec_functions)
+precmd_functions=(__rootcamp_precmd $precmd_functions)
+`
+
+func writeShellHooks(sandboxPath string) error {
+	stateDir := StateDir(sandboxPath)
+	zshDir := filepath.Join(stateDir, "zsh")
+	if err := os.MkdirAll(zshDir, 0700); err != nil {
+		return fmt.Errorf("failed to create shell hook directory: %w", err)
+	}
+
+	hooks := map[string]string{
+		filepath.Join(stateDir, "bashrc"): bashHook,
+		filepath.Join(zshDir, ".zshenv"):  zshEnvHook,
+		filepath.Join(zshDir, ".zshrc"):   zshRCHook,
+		historyPath(sandboxPath):          "",
+	}
+	for path, content := range hooks {
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+		}
+	}
+
+	return nil
+}
+
+// ShellCommand builds the interactive shell for a lab session. The shell
+// starts in the lesson's start directory and inherits the environment with
+// a few ROOTCAMP_* variables describing the lab. Bash and zsh get a hook
+// that records every command into the sandbox history; other shells are
+// swapped for bash when the lesson checks that history.
+func ShellCommand(sandboxPath string, lesson types.Lesson, useBasicBash bool) *exec.Cmd {
+	startPath := GetStartPath(sandboxPath, lesson)
+	stateDir := StateDir(sandboxPath)
+
+	shell := "bash"
+	if !useBasicBash {
+		shell = os.Getenv("SHELL")
+		if shell == "" {
+			shell = "/bin/bash"
+		}
+	}
+
+	env := append(labEnv(sandboxPath, lesson),
+		"TERM=xterm-256color",
+		fmt.Sprintf("PS1=rootcamp:%s$ ", lesson.Code),
+	)
+
+	kind := filepath.Base(shell)
+	if kind != "bash" && kind != "zsh" && NeedsHistory(lesson) {
+		shell = "bash"
+		kind = "bash"
+	}
+
+	launch := `exec "$ROOTCAMP_SHELL"`
+	switch kind {
+	case "bash":
+		launch = `exec "$ROOTCAMP_SHELL" --rcfile "$ROOTCAMP_STATE/bashrc"`
+	case "zsh":
+		zdotdir := os.Getenv("ZDOTDIR")
+		if zdotdir == "" {
+			zdotdir, _ = os.UserHomeDir()
+		}
+		env = append(env,
+			"ROOTCAMP_ZDOTDIR="+zdotdir,
+			"ZDOTDIR="+filepath.Join(stateDir, "zsh"),
+		)
+	}
+
+	c := exec.Command("bash", "-c", shellGreeting+launch)
+	c.Dir = startPath
+	c.Env = append(env, "ROOTCAMP_SHELL="+shell)
+
+	return c
+}
+
+// labEnv is the environment a lab shell runs in: the caller's own, plus the
+// ROOTCAMP_* variables the shell hooks rely on.
+func labEnv(sandboxPath string, lesson types.Lesson) []string {
+	return append(os.Environ(),
+		"ROOTCAMP_LESSON="+lesson.Title,
+		"ROOTCAMP_SANDBOX="+sandboxPath,
+		"ROOTCAMP_START="+GetStartPath(sandboxPath, lesson),
+		"ROOTCAMP_STATE="+StateDir(sandboxPath),
+		"ROOTCAMP_HISTORY="+historyPath(sandboxPath),
+	)
+}
@@ -0,0 +1,263 @@
+package lab
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/bobparsons/rootcamp/internal/types"
+)
+
+// snapshotDir keeps a copy of the sandbox exactly as Create built it, so the
+// learner can start over or get back a file they broke without losing the
+// rest of their work.
+func snapshotDir(sandboxPath string) string {
+	return filepath.Join(StateDir(sandboxPath), "snapshot")
+}
+
+func takeSnapshot(root, sandboxPath string) error {
+	if err := copyEntry(root, snapshotDir(sandboxPath)); err != nil {
+		return fmt.Errorf("failed to snapshot sandbox: %w", err)
+	}
+	return nil
+}
+
+// Reset puts the sandbox back the way it was when the lab started and
+// restarts the lesson's helper processes. The sandbox directory itself stays
+// in place so paths and mounts pointing at it remain valid.
+func Reset(sandboxPath string, lesson types.Lesson) error {
+	if !isSandboxPath(sandboxPath) {
+		return fmt.Errorf("invalid sandbox path: %s", sandboxPath)
+	}
+	snapshot := snapshotDir(sandboxPath)
+	snapInfo, err := os.Lstat(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to read sandbox snapshot: %w", err)
+	}
+
+	makeRemovable(sandboxPath)
+	entries, err := os.ReadDir(sandboxPath)
+	if err != nil {
+		return fmt.Errorf("failed to read sandbox: %w", err)
+	}
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(sandboxPath, entry.Name())); err != nil {
+			return fmt.Errorf("failed to clear sandbox: %w", err)
+		}
+	}
+
+	if err := copyChildren(snapshot, sandboxPath); err != nil {
+		return fmt.Errorf("failed to restore sandbox: %w", err)
+	}
+	if err := copyAttributes(snapInfo, sandboxPath); err != nil {
+		return fmt.Errorf("failed to restore sandbox: %w", err)
+	}
+
+	return restartProcesses(sandboxPath, lesson)
+}
+
+// RestoreFile replaces one path in the sandbox with its original version,
+// recreating any missing parent directories. Directories are restored with
+// everything in them.
+func RestoreFile(sandboxPath, relPath string) error {
+	if !isSandboxPath(sandboxPath) {
+		return fmt.Errorf("invalid sandbox path: %s", sandboxPath)
+	}
+	rel := filepath.Clean("/" + relPath)
+	if rel == "/" {
+		return fmt.Errorf("use Reset to restore the whole sandbox")
+	}
+
+	src := filepath.Join(snapshotDir(sandboxPath), rel)
+	if _, err := os.Lstat(src); err != nil {
+		return fmt.Errorf("%s was not part of the original sandbox", relPath)
+	}
+
+	dst := filepath.Join(sandboxPath, rel)
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("failed to recreate parent directory: %w", err)
+	}
+	makeRemovable(dst)
+	if err := os.RemoveAll(dst); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", relPath, err)
+	}
+	if err := copyEntry(src, dst); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", relPath, err)
+	}
+	return nil
+}
+
+// ChangedFiles lists the files and symlinks from the original sandbox that
+// are now missing or different, relative to the sandbox root.
+func ChangedFiles(sandboxPath string) ([]string, error) {
+	snapshot := snapshotDir(sandboxPath)
+	var changed []string
+	err := walkReadable(snapshot, func(path string, info os.FileInfo) error {
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(snapshot, path)
+		if err != nil {
+			return err
+		}
+		if !sameEntry(path, info, filepath.Join(sandboxPath, rel)) {
+			changed = append(changed, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare sandbox: %w", err)
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
+
+func sameEntry(origPath string, orig os.FileInfo, currentPath string) bool {
+	current, err := os.Lstat(currentPath)
+	if err != nil || current.Mode() != orig.Mode() {
+		return false
+	}
+	if orig.Mode()&os.ModeSymlink != 0 {
+		a, errA := os.Readlink(origPath)
+		b, errB := os.Readlink(currentPath)
+		return errA == nil && errB == nil && a == b
+	}
+	if current.Size() != orig.Size() {
+		return false
+	}
+	a, errA := os.ReadFile(origPath)
+	b, errB := os.ReadFile(currentPath)
+	return errA == nil && errB == nil && bytes.Equal(a, b)
+}
+
+// walkReadable visits a tree like filepath.Walk, parents before children,
+// temporarily opening up directories that lessons have locked down.
+func walkReadable(path string, fn func(string, os.FileInfo) error) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if err := fn(path, info); err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return nil
+	}
+
+	restore := openDir(path, info)
+	defer restore()
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := walkReadable(filepath.Join(path, entry.Name()), fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// openDir grants the owner access to a directory that lacks it and returns a
+// function that puts the original mode back.
+func openDir(path string, info os.FileInfo) func() {
+	if info.Mode().Perm()&0o700 == 0o700 {
+		return func() {}
+	}
+	os.Chmod(path, info.Mode().Perm()|0o700)
+	return func() { os.Chmod(path, fileMode(info)) }
+}
+
+func fileMode(info os.FileInfo) os.FileMode {
+	return info.Mode() & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky)
+}
+
+// copyEntry copies a file, symlink or directory tree, keeping modes and
+// modification times. Other file types are skipped.
+func copyEntry(src, dst string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(target, dst)
+
+	case info.IsDir():
+		if err := os.Mkdir(dst, 0700); err != nil {
+			return err
+		}
+		if err := copyChildren(src, dst); err != nil {
+			return err
+		}
+		return copyAttributes(info, dst)
+
+	case info.Mode().IsRegular():
+		if err := copyFile(src, dst, info); err != nil {
+			return err
+		}
+		return copyAttributes(info, dst)
+	}
+
+	return nil
+}
+
+func copyChildren(src, dst string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	restore := openDir(src, info)
+	defer restore()
+
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := copyEntry(filepath.Join(src, entry.Name()), filepath.Join(dst, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func copyFile(src, dst string, info os.FileInfo) error {
+	if info.Mode().Perm()&0o400 == 0 {
+		os.Chmod(src, info.Mode().Perm()|0o400)
+		defer os.Chmod(src, fileMode(info))
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func copyAttributes(info fs.FileInfo, dst string) error {
+	if err := os.Chmod(dst, fileMode(info)); err != nil {
+		return err
+	}
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
@@ -0,0 +1,311 @@
+package lab
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/bobparsons/rootcamp/internal/types"
+)
+
+// StateRequirement marks requirements that are graded by inspecting the
+// sandbox rather than the learner's answer.
+const StateRequirement = "sandbox_state"
+
+func IsStateRequirement(req types.Requirement) bool {
+	return req.Type == StateRequirement
+}
+
+// IsAnswerRequirement reports whether a requirement is checked against the
+// learner's typed answer.
+func IsAnswerRequirement(req types.Requirement) bool {
+	if IsGroup(req) && req.Type == "" {
+		for _, member := range groupMembers(req) {
+			if IsAnswerRequirement(member) {
+				return true
+			}
+		}
+		return false
+	}
+	return !IsStateRequirement(req) && !IsHistoryRequirement(req)
+}
+
+// NeedsAnswer reports whether the learner has to type an answer for the
+// lesson, or whether it is graded on the sandbox alone. A multi-step lesson
+// needs one if any of its steps does.
+func NeedsAnswer(lesson types.Lesson) bool {
+	for i := range lesson.Steps {
+		if NeedsAnswer(AtStep(lesson, i)) {
+			return true
+		}
+	}
+	if len(lesson.Steps) > 0 {
+		return false
+	}
+	for _, req := range lesson.Requirements {
+		if IsAnswerRequirement(req) {
+			return true
+		}
+	}
+	return len(lesson.Requirements) == 0
+}
+
+func sandboxFile(sandboxPath, relPath string) (string, bool) {
+	if sandboxPath == "" {
+		return "", false
+	}
+	return sandboxJoin(sandboxPath, relPath), true
+}
+
+func validatePathExists(relPath, sandboxPath string) bool {
+	fullPath, ok := sandboxFile(sandboxPath, relPath)
+	if !ok {
+		return false
+	}
+	info, err := os.Lstat(fullPath)
+	return err == nil && !info.IsDir()
+}
+
+func validatePathAbsent(relPath, sandboxPath string) bool {
+	fullPath, ok := sandboxFile(sandboxPath, relPath)
+	if !ok {
+		return false
+	}
+	_, err := os.Lstat(fullPath)
+	return os.IsNotExist(err)
+}
+
+func validateDirExists(relPath, sandboxPath string) bool {
+	fullPath, ok := sandboxFile(sandboxPath, relPath)
+	if !ok {
+		return false
+	}
+	info, err := os.Lstat(fullPath)
+	return err == nil && info.IsDir()
+}
+
+func readSandboxFile(relPath, sandboxPath string) (string, bool) {
+	fullPath, ok := sandboxFile(sandboxPath, relPath)
+	if !ok {
+		return "", false
+	}
+	content, err := os.ReadFile(fullPath)
+	if err != nil {
+		return "", false
+	}
+	return string(content), true
+}
+
+func validateFileContains(relPath, expected, sandboxPath string) bool {
+	content, ok := readSandboxFile(relPath, sandboxPath)
+	return ok && strings.Contains(content, expected)
+}
+
+func validateFileEquals(relPath, expected, sandboxPath string) bool {
+	content, ok := readSandboxFile(relPath, sandboxPath)
+	return ok && strings.TrimRight(content, "\n") == strings.TrimRight(expected, "\n")
+}
+
+func validateFileMatches(relPath, pattern, sandboxPath string) bool {
+	content, ok := readSandboxFile(relPath, sandboxPath)
+	if !ok {
+		return false
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return false
+	}
+	return re.MatchString(content)
+}
+
+func validateSymlinkTarget(relPath, expected, sandboxPath string) bool {
+	fullPath, ok := sandboxFile(sandboxPath, relPath)
+	if !ok {
+		return false
+	}
+	target, err := os.Readlink(fullPath)
+	if err != nil {
+		return false
+	}
+	return filepath.Clean(target) == filepath.Clean(expected)
+}
+
+func validateFileMode(relPath, expected, sandboxPath string) bool {
+	fullPath, ok := sandboxFile(sandboxPath, relPath)
+	if !ok {
+		return false
+	}
+	info, err := os.Lstat(fullPath)
+	if err != nil {
+		return false
+	}
+	matched, err := MatchMode(info.Mode(), expected)
+	return err == nil && matched
+}
+
+// validateModifiedAfter checks that the path was modified after the given
+// time, typically the mtime the lesson seeded it with.
+func validateModifiedAfter(relPath, expected, sandboxPath string) bool {
+	fullPath, ok := sandboxFile(sandboxPath, relPath)
+	if !ok {
+		return false
+	}
+	info, err := os.Lstat(fullPath)
+	if err != nil {
+		return false
+	}
+	after, err := ParseSandboxTime(expected)
+	if err != nil {
+		return false
+	}
+	return info.ModTime().After(after)
+}
+
+// MatchMode checks permission bits against either an octal mode such as
+// "0755", which must match exactly, or a comma separated list of symbolic
+// clauses such as "u+x,go-w" or "g=rx", where + requires the bits, - forbids
+// them and = requires exactly those bits for the named classes.
+func MatchMode(mode fs.FileMode, expected string) (bool, error) {
+	expected = strings.TrimSpace(expected)
+	perm := uint32(mode.Perm())
+	if mode&fs.ModeSetuid != 0 {
+		perm |= 0o4000
+	}
+	if mode&fs.ModeSetgid != 0 {
+		perm |= 0o2000
+	}
+	if mode&fs.ModeSticky != 0 {
+		perm |= 0o1000
+	}
+
+	if octal, err := strconv.ParseUint(expected, 8, 32); err == nil {
+		return perm == uint32(octal), nil
+	}
+
+	for _, clause := range strings.Split(expected, ",") {
+		opIdx := strings.IndexAny(clause, "+-=")
+		if opIdx < 0 {
+			return false, fmt.Errorf("invalid mode clause %q", clause)
+		}
+
+		who := clause[:opIdx]
+		if who == "" {
+			who = "a"
+		}
+		op := clause[opIdx]
+		permChars := clause[opIdx+1:]
+
+		var classes []uint32
+		for _, c := range who {
+			switch c {
+			case 'u':
+				classes = append(classes, 6)
+			case 'g':
+				classes = append(classes, 3)
+			case 'o':
+				classes = append(classes, 0)
+			case 'a':
+				classes = append(classes, 6, 3, 0)
+			default:
+				return false, fmt.Errorf("invalid mode class %q in %q", c, clause)
+			}
+		}
+
+		var bits uint32
+		for _, c := range permChars {
+			switch c {
+			case 'r':
+				bits |= 4
+			case 'w':
+				bits |= 2
+			case 'x':
+				bits |= 1
+			default:
+				return false, fmt.Errorf("invalid permission %q in %q", c, clause)
+			}
+		}
+
+		for _, shift := range classes {
+			actual := perm >> shift & 7
+			switch op {
+			case '+':
+				if actual&bits != bits {
+					return false, nil
+				}
+			case '-':
+				if actual&bits != 0 {
+					return false, nil
+				}
+			case '=':
+				if actual != bits {
+					return false, nil
+				}
+			}
+		}
+	}
+
+	return true, nil
+}
+
+// treeMatchScore checks that the directory at relPath contains exactly the
+// listed entries. Entries are slash separated paths relative to that
+// directory, with directories written with a trailing slash. The score is
+// the share of entries found, reduced by any unexpected extras.
+func treeMatchScore(relPath string, entries []string, sandboxPath string) float64 {
+	if relPath == "" {
+		relPath = "."
+	}
+	root, ok := sandboxFile(sandboxPath, relPath)
+	if !ok {
+		return 0
+	}
+
+	actual, err := listTree(root)
+	if err != nil {
+		return 0
+	}
+
+	matched := 0
+	for _, entry := range entries {
+		if slices.Contains(actual, strings.TrimPrefix(entry, "./")) {
+			matched++
+		}
+	}
+	total := len(entries) + len(actual) - matched
+	if total == 0 {
+		return 1
+	}
+	return float64(matched) / float64(total)
+}
+
+func listTree(root string) ([]string, error) {
+	var entries []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == root {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			rel += "/"
+		}
+		entries = append(entries, rel)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(entries)
+	return entries, nil
+}
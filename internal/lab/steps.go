@@ -0,0 +1,61 @@
+package lab
+
+import (
+	"strconv"
+
+	"github.com/bobparsons/rootcamp/internal/types"
+)
+
+// StepID returns the ID a step's progress is saved under. Steps without one
+// are known by their position.
+func StepID(lesson types.Lesson, step int) string {
+	if id := lesson.Steps[step].ID; id != "" {
+		return id
+	}
+	return strconv.Itoa(step + 1)
+}
+
+// AtStep returns the lesson as it is graded at the given step, with that
+// step's requirements in place of its own. Lessons without steps are
+// returned unchanged.
+func AtStep(lesson types.Lesson, step int) types.Lesson {
+	if len(lesson.Steps) == 0 {
+		return lesson
+	}
+	step = min(max(step, 0), len(lesson.Steps)-1)
+	lesson.Requirements = lesson.Steps[step].Requirements
+	lesson.Steps = nil
+	return lesson
+}
+
+// CheckSteps works through a multi-step lesson from the first unfinished
+// step and returns how many steps are now done, along with the reason the
+// next one failed. Steps that passed stay done even if the learner later
+// undoes them. The answer counts towards the first step that asks for one,
+// so checking stops at any later step that needs another.
+func CheckSteps(lesson types.Lesson, done int, userInput, sandboxPath string) (int, string) {
+	answered := false
+	for ; done < len(lesson.Steps); done++ {
+		step := AtStep(lesson, done)
+		if NeedsAnswer(step) {
+			if answered {
+				return done, ""
+			}
+			answered = true
+		}
+		if ok, msg := ValidateLesson(step, userInput, sandboxPath); !ok {
+			return done, msg
+		}
+	}
+	return done, ""
+}
+
+// AllRequirements returns every check in the lesson, across its steps and
+// inside groups.
+func AllRequirements(lesson types.Lesson) []types.Requirement {
+	reqs := leafRequirements(lesson.Requirements)
+	for _, step := range lesson.Steps {
+		reqs = append(reqs, leafRequirements(step.Requirements)...)
+	}
+	return reqs
+}
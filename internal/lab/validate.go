@@ -9,32 +9,145 @@ import (
 	"github.com/bobparsons/rootcamp/internal/types"
 )
 
-func ValidateRequirement(req types.Requirement, userInput, sandboxPath string) bool {
-	switch req.Validator {
-	case "exact":
-		return validateExact(req.Expected, userInput)
-	case "path_match":
-		return validatePathMatch(req.Expected, userInput, sandboxPath)
-	case "file_check":
-		return validateFileExists(req.Expected, sandboxPath)
-	case "regex":
-		return validateRegex(req.Expected, userInput)
-	default:
-		return false
+func init() {
+	builtins := map[string]Validator{
+		"exact": passFail(func(c Check) bool {
+			return validateExact(c.Requirement.Expected, c.Input)
+		}),
+		"path_match": passFail(func(c Check) bool {
+			return validatePathMatch(c.Requirement.Expected, c.Input, c.SandboxPath)
+		}),
+		"file_check": passFail(func(c Check) bool {
+			return validateFileExists(c.Requirement.Expected, c.SandboxPath)
+		}),
+		"regex": passFail(func(c Check) bool {
+			return validateRegex(c.Requirement.Expected, c.Input)
+		}),
+		"computed": passFail(func(c Check) bool {
+			return validateComputed(c.Requirement, c.Input, c.SandboxPath)
+		}),
+		"file_exists": passFail(func(c Check) bool {
+			return validatePathExists(c.Requirement.Path, c.SandboxPath)
+		}),
+		"file_absent": passFail(func(c Check) bool {
+			return validatePathAbsent(c.Requirement.Path, c.SandboxPath)
+		}),
+		"dir_exists": passFail(func(c Check) bool {
+			return validateDirExists(c.Requirement.Path, c.SandboxPath)
+		}),
+		"file_contains": passFail(func(c Check) bool {
+			return validateFileContains(c.Requirement.Path, c.Requirement.Expected, c.SandboxPath)
+		}),
+		"file_equals": passFail(func(c Check) bool {
+			return validateFileEquals(c.Requirement.Path, c.Requirement.Expected, c.SandboxPath)
+		}),
+		"file_matches": passFail(func(c Check) bool {
+			return validateFileMatches(c.Requirement.Path, c.Requirement.Expected, c.SandboxPath)
+		}),
+		"symlink_target": passFail(func(c Check) bool {
+			return validateSymlinkTarget(c.Requirement.Path, c.Requirement.Expected, c.SandboxPath)
+		}),
+		"file_mode": passFail(func(c Check) bool {
+			return validateFileMode(c.Requirement.Path, c.Requirement.Expected, c.SandboxPath)
+		}),
+		"modified_after": passFail(func(c Check) bool {
+			return validateModifiedAfter(c.Requirement.Path, c.Requirement.Expected, c.SandboxPath)
+		}),
+		"tree_matches": scored(func(c Check) float64 {
+			return treeMatchScore(c.Requirement.Path, c.Requirement.Entries, c.SandboxPath)
+		}),
+		"archive_contains": scored(func(c Check) float64 {
+			return archiveContainsScore(c.Requirement.Path, c.Requirement.Entries, c.SandboxPath)
+		}),
+		"archive_matches": passFail(func(c Check) bool {
+			return validateArchiveMatches(c.Requirement.Path, c.Requirement.Entries, c.SandboxPath)
+		}),
+		"archive_member_contains": passFail(func(c Check) bool {
+			return validateArchiveMemberContains(c.Requirement.Path, c.Requirement.Member, c.Requirement.Expected, c.SandboxPath)
+		}),
+		"process_signaled": passFail(func(c Check) bool {
+			return validateProcessSignaled(c.Requirement.Process, c.Requirement.Expected, c.SandboxPath)
+		}),
+		"process_running": passFail(func(c Check) bool {
+			return validateProcessRunning(c.Requirement.Process, c.SandboxPath)
+		}),
+		"command_used": passFail(func(c Check) bool {
+			return validateCommandUsed(c.Requirement.Expected, c.SandboxPath)
+		}),
+		"command_matches_regex": passFail(func(c Check) bool {
+			return validateCommandMatchesRegex(c.Requirement.Expected, c.SandboxPath)
+		}),
+		"flag_used": passFail(func(c Check) bool {
+			return validateFlagUsed(c.Requirement.Command, c.Requirement.Expected, c.Requirement.Entries, c.SandboxPath)
+		}),
+		"command_equivalent": passFail(func(c Check) bool {
+			return validateCommandEquivalent(c.Requirement.Expected, c.SandboxPath)
+		}),
+	}
+	for name, v := range builtins {
+		RegisterValidator(name, v)
 	}
 }
 
+// Evaluate checks a requirement, or a group of them, and returns the
+// structured result. A group's score averages an all group's members and
+// takes the best member of an any group.
+func Evaluate(req types.Requirement, userInput, sandboxPath string) Result {
+	failures, score := checkRequirement(req, userInput, sandboxPath)
+	return Result{
+		Passed:  len(failures) == 0,
+		Message: strings.Join(failures, "; "),
+		Score:   score,
+	}
+}
+
+func ValidateRequirement(req types.Requirement, userInput, sandboxPath string) bool {
+	return Evaluate(req, userInput, sandboxPath).Passed
+}
+
 func validateExact(expected, actual string) bool {
+	// An answer that was never filled in must not be matched by typing the
+	// placeholder itself.
+	if strings.Contains(expected, SecretPlaceholder) {
+		return false
+	}
 	return strings.TrimSpace(expected) == strings.TrimSpace(actual)
 }
 
+// SandboxPlaceholder stands for the sandbox path in expected values, so
+// lessons do not depend on where sandboxes are created. The older
+// "/tmp/rootcamp-{uuid}" form is still accepted.
+const SandboxPlaceholder = "{sandbox}"
+
+const legacySandboxPlaceholder = "/tmp/rootcamp-{uuid}"
+
+// validatePathMatch compares a path the learner reports against the
+// expected one. The sandbox may sit below a symlink, such as /tmp on macOS,
+// in which case pwd -P shows the resolved path, so that form matches too.
 func validatePathMatch(expectedTemplate, actual, sandboxPath string) bool {
-	expected := strings.ReplaceAll(expectedTemplate, "/tmp/rootcamp-{uuid}", sandboxPath)
-	return strings.TrimSpace(expected) == strings.TrimSpace(actual)
+	actual = strings.TrimSpace(actual)
+	if actual == "" {
+		return false
+	}
+	actual = filepath.Clean(actual)
+
+	bases := []string{sandboxPath}
+	if resolved, err := filepath.EvalSymlinks(sandboxPath); err == nil && resolved != sandboxPath {
+		bases = append(bases, resolved)
+	}
+
+	for _, base := range bases {
+		expected := strings.ReplaceAll(expectedTemplate, legacySandboxPlaceholder, base)
+		expected = strings.ReplaceAll(expected, SandboxPlaceholder, base)
+		if filepath.Clean(strings.TrimSpace(expected)) == actual {
+			return true
+		}
+	}
+	return false
 }
 
 func validateFileExists(filename, sandboxPath string) bool {
-	fullPath := filepath.Join(sandboxPath, filename)
+	fullPath := sandboxJoin(sandboxPath, filename)
 	_, err := os.Stat(fullPath)
 	return err == nil
 }
@@ -47,16 +160,55 @@ func validateRegex(pattern, actual string) bool {
 	return re.MatchString(strings.TrimSpace(actual))
 }
 
+// ValidateLesson passes when every sandbox state and command history
+// requirement holds and, if the lesson asks for an answer, at least one
+// answer requirement matches. A multi-step lesson passes when all of its
+// steps do. On failure it gives every reason from LessonFailures.
 func ValidateLesson(lesson types.Lesson, userInput, sandboxPath string) (bool, string) {
+	failures := LessonFailures(lesson, userInput, sandboxPath)
+	return len(failures) == 0, strings.Join(failures, "; ")
+}
+
+// LessonFailures lists why a lesson does not pass yet: each failing sandbox
+// state and command history check, followed by the first answer
+// requirement if no answer matches. It is empty once the lesson passes.
+func LessonFailures(lesson types.Lesson, userInput, sandboxPath string) []string {
+	if len(lesson.Steps) > 0 {
+		done, msg := CheckSteps(lesson, 0, userInput, sandboxPath)
+		if done == len(lesson.Steps) {
+			return nil
+		}
+		if msg == "" {
+			msg = lesson.Steps[done].Title
+		}
+		return []string{msg}
+	}
+
+	if len(lesson.Requirements) == 0 {
+		return []string{"No requirements defined"}
+	}
+
+	var failures []string
+	var answers []types.Requirement
 	for _, req := range lesson.Requirements {
-		if ValidateRequirement(req, userInput, sandboxPath) {
-			return true, ""
+		if IsAnswerRequirement(req) {
+			answers = append(answers, req)
+			continue
 		}
+		reasons, _ := checkRequirement(req, userInput, sandboxPath)
+		failures = append(failures, reasons...)
 	}
 
-	if len(lesson.Requirements) > 0 {
-		return false, lesson.Requirements[0].Description
+	if len(answers) == 0 {
+		return failures
+	}
+
+	for _, req := range answers {
+		if ValidateRequirement(req, userInput, sandboxPath) {
+			return failures
+		}
 	}
 
-	return false, "No requirements defined"
+	reasons, _ := checkRequirement(answers[0], userInput, sandboxPath)
+	return append(failures, reasons...)
 }
@@ -0,0 +1,139 @@
+package lab
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/bobparsons/rootcamp/internal/types"
+)
+
+// Check is what a validator is given: the requirement being checked, the
+// answer the learner typed and the sandbox they worked in.
+type Check struct {
+	Requirement types.Requirement
+	Input       string
+	SandboxPath string
+}
+
+// Result is the outcome of a validator. Score gives partial credit between
+// 0 and 1, such as the share of expected files that were found. A failing
+// result with no Message is explained from the requirement.
+type Result struct {
+	Passed  bool
+	Message string
+	Score   float64
+}
+
+type Validator interface {
+	Validate(c Check) Result
+}
+
+// ValidatorFunc lets a plain function be registered as a Validator.
+type ValidatorFunc func(c Check) Result
+
+func (f ValidatorFunc) Validate(c Check) Result {
+	return f(c)
+}
+
+var (
+	validatorsMu sync.RWMutex
+	validators   = map[string]Validator{}
+)
+
+// RegisterValidator makes a validator available to lessons under the given
+// name. Registering a name twice panics, so one lesson pack cannot quietly
+// replace another's validator. Call it from an init function, before any
+// lessons are loaded.
+func RegisterValidator(name string, v Validator) {
+	validatorsMu.Lock()
+	defer validatorsMu.Unlock()
+	if name == "" || v == nil {
+		panic("lab: RegisterValidator needs a name and a validator")
+	}
+	if _, dup := validators[name]; dup {
+		panic(fmt.Sprintf("lab: validator %q registered twice", name))
+	}
+	validators[name] = v
+}
+
+func lookupValidator(name string) (Validator, bool) {
+	validatorsMu.RLock()
+	defer validatorsMu.RUnlock()
+	v, ok := validators[name]
+	return v, ok
+}
+
+// ValidatorNames lists the registered validators in order.
+func ValidatorNames() []string {
+	validatorsMu.RLock()
+	defer validatorsMu.RUnlock()
+	names := make([]string, 0, len(validators))
+	for name := range validators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// passFail wraps a check that has no notion of partial credit.
+func passFail(check func(c Check) bool) Validator {
+	return ValidatorFunc(func(c Check) Result {
+		if check(c) {
+			return Result{Passed: true, Score: 1}
+		}
+		return Result{}
+	})
+}
+
+// scored wraps a check that gives partial credit, passing only with full
+// marks.
+func scored(score func(c Check) float64) Validator {
+	return ValidatorFunc(func(c Check) Result {
+		s := score(c)
+		return Result{Passed: s >= 1, Score: s}
+	})
+}
+
+// runValidator checks a single requirement with its registered validator.
+// Failures without a message of their own get the requirement's
+// description, with sandbox state checks adding what the sandbox shows.
+func runValidator(req types.Requirement, userInput, sandboxPath string) Result {
+	v, ok := lookupValidator(req.Validator)
+	if !ok {
+		return Result{Message: fmt.Sprintf("unknown validator %q", req.Validator)}
+	}
+	result := v.Validate(Check{Requirement: req, Input: userInput, SandboxPath: sandboxPath})
+	if result.Passed {
+		result.Score = 1
+		result.Message = ""
+		return result
+	}
+	result.Score = min(max(result.Score, 0), 1)
+	if result.Message == "" {
+		if IsStateRequirement(req) {
+			result.Message = explainFailure(req, sandboxPath)
+		} else {
+			result.Message = req.Description
+		}
+	}
+	return result
+}
+
+// CheckValidators reports requirements in the lesson, including those in
+// steps and groups, whose validator is missing or not registered.
+func CheckValidators(lesson types.Lesson) error {
+	reqs := AllRequirements(lesson)
+	var errs []error
+	for _, req := range reqs {
+		if req.Validator == "" {
+			errs = append(errs, fmt.Errorf("requirement %q has no validator", req.Description))
+			continue
+		}
+		if _, ok := lookupValidator(req.Validator); !ok {
+			errs = append(errs, fmt.Errorf("requirement %q uses unknown validator %q", req.Description, req.Validator))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -4,76 +4,187 @@ import (
 	"embed"
 	"encoding/json"
 	"fmt"
+	"io/fs"
+	"path"
+	"strings"
 
 	"github.com/bobparsons/rootcamp/internal/types"
 )
 
-//go:embed data/course.json
-var embeddedCourseFS embed.FS
+//go:embed data/courses/*.json
+var embeddedCoursesFS embed.FS
 
-var cachedCourse *types.CourseData
+var cachedCourses []types.Course
 
-func LoadCourse() (*types.CourseData, error) {
-	if cachedCourse != nil {
-		return cachedCourse, nil
+// LoadCourses returns the built-in courses, one per file in data/courses,
+// followed by the courses that came with packs. A lesson may appear in any
+// number of courses.
+func LoadCourses() ([]types.Course, error) {
+	if cachedCourses != nil {
+		return cachedCourses, nil
 	}
 
-	content, err := embeddedCourseFS.ReadFile("data/course.json")
+	builtin, err := readCourses(embeddedCoursesFS, "data/courses")
 	if err != nil {
-		return nil, fmt.Errorf("failed to read course.json: %w", err)
+		return nil, err
 	}
 
-	var courseData types.CourseData
-	if err := json.Unmarshal(content, &courseData); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal course: %w", err)
+	cachedCourses = append(builtin, packCourses(builtin)...)
+	return cachedCourses, nil
+}
+
+// GetCourse returns the course with the given ID.
+func GetCourse(id string) (*types.Course, error) {
+	courses, err := LoadCourses()
+	if err != nil {
+		return nil, err
 	}
+	for i := range courses {
+		if courses[i].ID == id {
+			return &courses[i], nil
+		}
+	}
+	return nil, fmt.Errorf("course %s not found", id)
+}
 
-	cachedCourse = &courseData
-	return cachedCourse, nil
+// readCourses loads every course file in dir.
+func readCourses(fsys fs.FS, dir string) ([]types.Course, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read courses directory: %w", err)
+	}
+
+	courses := []types.Course{}
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") {
+			courseData, err := readCourse(fsys, path.Join(dir, entry.Name()))
+			if err != nil {
+				return nil, err
+			}
+			courses = append(courses, courseData.Course)
+		}
+	}
+	return courses, nil
 }
 
-func GetCourseLessons(progressMap map[string]*types.UserProgress, allLessons []types.Lesson) ([]types.CourseLessonItem, error) {
-	courseData, err := LoadCourse()
+func readCourse(fsys fs.FS, filePath string) (*types.CourseData, error) {
+	content, err := fs.ReadFile(fsys, filePath)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to read %s: %w", path.Base(filePath), err)
+	}
+
+	var courseData types.CourseData
+	if err := json.Unmarshal(content, &courseData); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal course %s: %w", path.Base(filePath), err)
 	}
+	if courseData.Course.ID == "" {
+		return nil, fmt.Errorf("course in %s has no id", path.Base(filePath))
+	}
+	return &courseData, nil
+}
 
+// GetCourseLessons lists the course's lessons with their status. A lesson
+// unlocks once all of its prerequisites are complete or tested out, so
+// learners can work on any branch of the course whose prerequisites they
+// have met.
+// Prerequisites the course does not include are ignored, and a lesson that
+// declares none follows the lesson listed before it. Progress is kept per
+// lesson, so a lesson finished in one course counts as complete in every
+// course that includes it.
+func GetCourseLessons(course types.Course, progressMap map[string]*types.UserProgress, allLessons []types.Lesson) []types.CourseLessonItem {
 	lessonMap := make(map[string]*types.Lesson)
 	for i := range allLessons {
 		lessonMap[allLessons[i].ID] = &allLessons[i]
 	}
 
+	// Course entries that refer to a missing lesson are dropped first, so
+	// they do not shift a lesson's implied prerequisite onto the wrong one.
 	var courseLessons []types.CourseLessonItem
-
-	for idx, courseLessonRef := range courseData.Course.Lessons {
+	index := make(map[string]int)
+	for _, courseLessonRef := range course.Lessons {
 		lesson, exists := lessonMap[courseLessonRef.LessonId]
 		if !exists {
 			continue
 		}
+		if _, dup := index[lesson.ID]; dup {
+			continue
+		}
+		index[lesson.ID] = len(courseLessons)
+		courseLessons = append(courseLessons, types.CourseLessonItem{
+			Lesson:   *lesson,
+			Sequence: courseLessonRef.Sequence,
+		})
+	}
 
-		status := types.LessonLocked
-
-		if idx == 0 {
-			status = types.LessonUnlocked
-		} else if idx > 0 {
-			prevLesson := &courseLessons[idx-1]
-			if prevLesson.Status == types.LessonComplete {
-				status = types.LessonUnlocked
+	for i := range courseLessons {
+		item := &courseLessons[i]
+		if item.Lesson.Prerequisites == nil {
+			if i > 0 {
+				item.Prerequisites = []string{courseLessons[i-1].Lesson.ID}
+			}
+			continue
+		}
+		for _, id := range item.Lesson.Prerequisites {
+			if _, ok := index[id]; ok && id != item.Lesson.ID {
+				item.Prerequisites = append(item.Prerequisites, id)
 			}
 		}
+	}
 
-		if progress, exists := progressMap[lesson.ID]; exists && progress.Completed {
-			status = types.LessonComplete
+	complete := func(id string) bool {
+		progress, exists := progressMap[id]
+		return exists && progress.Completed
+	}
+	testedOut := func(id string) bool {
+		progress, exists := progressMap[id]
+		return exists && progress.TestedOut
+	}
+
+	depths := make(map[int]int)
+	for i := range courseLessons {
+		item := &courseLessons[i]
+		item.Depth = prerequisiteDepth(courseLessons, index, i, depths, map[int]bool{})
+
+		item.Status = types.LessonUnlocked
+		for _, id := range item.Prerequisites {
+			if !complete(id) && !testedOut(id) {
+				item.Status = types.LessonLocked
+				break
+			}
 		}
+		if testedOut(item.Lesson.ID) {
+			item.Status = types.LessonTestedOut
+		}
+		if complete(item.Lesson.ID) {
+			item.Status = types.LessonComplete
+		}
+	}
 
-		courseLessons = append(courseLessons, types.CourseLessonItem{
-			Lesson:   *lesson,
-			Status:   status,
-			Sequence: courseLessonRef.Sequence,
-		})
+	return courseLessons
+}
+
+// prerequisiteDepth returns the length of the longest chain of prerequisites
+// leading to the lesson at i. A cycle is cut where it closes; lint reports
+// it, and the lessons in it stay locked.
+func prerequisiteDepth(items []types.CourseLessonItem, index map[string]int, i int, depths map[int]int, visiting map[int]bool) int {
+	if depth, ok := depths[i]; ok {
+		return depth
 	}
+	visiting[i] = true
+	defer delete(visiting, i)
 
-	return courseLessons, nil
+	depth := 0
+	for _, id := range items[i].Prerequisites {
+		j := index[id]
+		if visiting[j] {
+			continue
+		}
+		if d := prerequisiteDepth(items, index, j, depths, visiting) + 1; d > depth {
+			depth = d
+		}
+	}
+	depths[i] = depth
+	return depth
 }
 
 func GetNextUnlockedLesson(courseLessons []types.CourseLessonItem) *types.CourseLessonItem {
@@ -94,3 +205,15 @@ func GetCourseProgress(courseLessons []types.CourseLessonItem) (int, int) {
 	}
 	return completed, len(courseLessons)
 }
+
+// GetCourseTestedOut counts the course's lessons that were tested out of
+// rather than completed.
+func GetCourseTestedOut(courseLessons []types.CourseLessonItem) int {
+	testedOut := 0
+	for _, item := range courseLessons {
+		if item.Status == types.LessonTestedOut {
+			testedOut++
+		}
+	}
+	return testedOut
+}
@@ -4,8 +4,9 @@ import (
 	"embed"
 	"encoding/json"
 	"fmt"
+	"io/fs"
 	"math/rand"
-	"path/filepath"
+	"path"
 	"slices"
 	"strings"
 
@@ -22,42 +23,53 @@ func LoadFunFacts() (*types.FunFactsData, error) {
 		return cachedFacts, nil
 	}
 
+	builtin, err := readFacts(embeddedFunFactsFS, "data/funfacts")
+	if err != nil {
+		return nil, err
+	}
+
 	data := &types.FunFactsData{
 		Version: "1.0",
-		Facts:   []types.FunFact{},
+		Facts:   builtin,
 	}
+	data.Facts = append(data.Facts, packFacts(builtin)...)
 
-	entries, err := embeddedFunFactsFS.ReadDir("data/funfacts")
+	cachedFacts = data
+	return cachedFacts, nil
+}
+
+// readFacts loads every fun fact file in dir.
+func readFacts(fsys fs.FS, dir string) ([]types.FunFact, error) {
+	entries, err := fs.ReadDir(fsys, dir)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read funfacts directory: %w", err)
 	}
 
+	facts := []types.FunFact{}
 	for _, entry := range entries {
 		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") {
-			if err := loadFactsFromFile(entry.Name(), data); err != nil {
+			fileFacts, err := loadFactsFromFile(fsys, path.Join(dir, entry.Name()))
+			if err != nil {
 				return nil, fmt.Errorf("failed to load %s: %w", entry.Name(), err)
 			}
+			facts = append(facts, fileFacts...)
 		}
 	}
-
-	cachedFacts = data
-	return cachedFacts, nil
+	return facts, nil
 }
 
-func loadFactsFromFile(filename string, accumulator *types.FunFactsData) error {
-	filePath := filepath.Join("data/funfacts", filename)
-	content, err := embeddedFunFactsFS.ReadFile(filePath)
+func loadFactsFromFile(fsys fs.FS, filePath string) ([]types.FunFact, error) {
+	content, err := fs.ReadFile(fsys, filePath)
 	if err != nil {
-		return fmt.Errorf("read file error: %w", err)
+		return nil, fmt.Errorf("read file error: %w", err)
 	}
 
 	var fileData types.FunFactsData
 	if err := json.Unmarshal(content, &fileData); err != nil {
-		return fmt.Errorf("unmarshal error: %w", err)
+		return nil, fmt.Errorf("unmarshal error: %w", err)
 	}
 
-	accumulator.Facts = append(accumulator.Facts, fileData.Facts...)
-	return nil
+	return fileData.Facts, nil
 }
 
 func GetRandomFact() (*types.FunFact, error) {
@@ -4,10 +4,12 @@ import (
 	"embed"
 	"encoding/json"
 	"fmt"
-	"path/filepath"
+	"io/fs"
+	"path"
 	"slices"
 	"strings"
 
+	"github.com/bobparsons/rootcamp/internal/lab"
 	"github.com/bobparsons/rootcamp/internal/types"
 )
 
@@ -21,42 +23,65 @@ func LoadLessons() (*types.LessonsData, error) {
 		return cachedLessons, nil
 	}
 
+	builtin, err := readLessons(embeddedLessonsFS, "data/lessons")
+	if err != nil {
+		return nil, err
+	}
+
 	data := &types.LessonsData{
 		Version: "1.0",
-		Lessons: []types.Lesson{},
+		Lessons: builtin,
 	}
+	data.Lessons = append(data.Lessons, loadPacks(builtin)...)
+
+	cachedLessons = data
+	return cachedLessons, nil
+}
 
-	entries, err := embeddedLessonsFS.ReadDir("data/lessons")
+// readLessons loads every lesson file in dir.
+func readLessons(fsys fs.FS, dir string) ([]types.Lesson, error) {
+	entries, err := fs.ReadDir(fsys, dir)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read lessons directory: %w", err)
 	}
 
+	lessons := []types.Lesson{}
 	for _, entry := range entries {
 		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") {
-			if err := loadLessonsFromFile(entry.Name(), data); err != nil {
+			fileLessons, err := loadLessonsFromFile(fsys, path.Join(dir, entry.Name()))
+			if err != nil {
 				return nil, fmt.Errorf("failed to load %s: %w", entry.Name(), err)
 			}
+			lessons = append(lessons, fileLessons...)
 		}
 	}
-
-	cachedLessons = data
-	return cachedLessons, nil
+	return lessons, nil
 }
 
-func loadLessonsFromFile(filename string, accumulator *types.LessonsData) error {
-	filePath := filepath.Join("data/lessons", filename)
-	content, err := embeddedLessonsFS.ReadFile(filePath)
+func loadLessonsFromFile(fsys fs.FS, filePath string) ([]types.Lesson, error) {
+	content, err := fs.ReadFile(fsys, filePath)
 	if err != nil {
-		return fmt.Errorf("read file error: %w", err)
+		return nil, fmt.Errorf("read file error: %w", err)
 	}
 
 	var fileData types.LessonsData
 	if err := json.Unmarshal(content, &fileData); err != nil {
-		return fmt.Errorf("unmarshal error: %w", err)
+		return nil, fmt.Errorf("unmarshal error: %w", err)
+	}
+
+	for _, lesson := range fileData.Lessons {
+		if err := lab.CheckValidators(lesson); err != nil {
+			return nil, fmt.Errorf("lesson %s: %w", lesson.ID, err)
+		}
+		if err := lab.CheckQuiz(lesson); err != nil {
+			return nil, fmt.Errorf("lesson %s: %w", lesson.ID, err)
+		}
+		if err := lab.CheckSandboxPaths(lesson); err != nil {
+			return nil, fmt.Errorf("lesson %s: %w", lesson.ID, err)
+		}
 	}
 
-	accumulator.Lessons = append(accumulator.Lessons, fileData.Lessons...)
-	return nil
+	return fileData.Lessons, nil
 }
 
 func GetLessonByID(id string) (*types.Lesson, error) {
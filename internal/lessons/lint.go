@@ -0,0 +1,486 @@
+package lessons
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/bobparsons/rootcamp/internal/lab"
+	"github.com/bobparsons/rootcamp/internal/types"
+)
+
+// Issue is a problem found by Lint. Warnings point at something that loads
+// but is probably a mistake; anything else stops a lesson, course or pack
+// from working.
+type Issue struct {
+	Source  string
+	File    string
+	Lesson  string
+	Message string
+	Warning bool
+}
+
+func (i Issue) String() string {
+	level := "error"
+	if i.Warning {
+		level = "warning"
+	}
+	where := i.Source
+	if i.File != "" {
+		where += ": " + i.File
+	}
+	if i.Lesson != "" {
+		where += ": " + i.Lesson
+	}
+	return fmt.Sprintf("%s: %s: %s", level, where, i.Message)
+}
+
+// lintSource is one set of lesson data: the built-in files or a pack.
+type lintSource struct {
+	name       string
+	pack       bool
+	lessonsFS  fs.FS
+	lessonsDir string
+	factsFS    fs.FS
+	factsDir   string
+	coursesFS  fs.FS
+	coursesDir string
+	coursePath string
+	root       fs.FS
+}
+
+type lintedLesson struct {
+	file   string
+	lesson types.Lesson
+}
+
+type linter struct {
+	issues         []Issue
+	builtin        map[string]bool
+	builtinCourses map[string]bool
+}
+
+func (l *linter) add(src lintSource, file, lesson, format string, args ...any) {
+	l.issues = append(l.issues, Issue{Source: src.name, File: file, Lesson: lesson, Message: fmt.Sprintf(format, args...)})
+}
+
+func (l *linter) warn(src lintSource, file, lesson, format string, args ...any) {
+	l.issues = append(l.issues, Issue{Source: src.name, File: file, Lesson: lesson, Message: fmt.Sprintf(format, args...), Warning: true})
+}
+
+// Lint checks the built-in lessons, courses and fun facts, then every pack,
+// without loading them. Files are decoded strictly, so misspelt keys are
+// reported rather than ignored.
+func Lint() []Issue {
+	l := &linter{builtin: map[string]bool{}, builtinCourses: map[string]bool{}}
+
+	builtin := lintSource{
+		name:       "built-in",
+		lessonsFS:  embeddedLessonsFS,
+		lessonsDir: "data/lessons",
+		factsFS:    embeddedFunFactsFS,
+		factsDir:   "data/funfacts",
+		coursesFS:  embeddedCoursesFS,
+		coursesDir: "data/courses",
+	}
+	for _, item := range l.lintSource(builtin) {
+		l.builtin[item.lesson.ID] = true
+	}
+
+	for _, dir := range packDirs() {
+		info, err := os.Stat(dir)
+		if err != nil || !info.IsDir() {
+			l.add(lintSource{name: dir}, "", "", "not a directory")
+			continue
+		}
+		fsys := os.DirFS(dir)
+		l.lintSource(lintSource{
+			name:       "pack " + filepath.Base(dir),
+			pack:       true,
+			lessonsFS:  fsys,
+			lessonsDir: "lessons",
+			factsFS:    fsys,
+			factsDir:   "funfacts",
+			coursesFS:  fsys,
+			coursesDir: "courses",
+			coursePath: "course.json",
+			root:       fsys,
+		})
+	}
+	return l.issues
+}
+
+func (l *linter) lintSource(src lintSource) []lintedLesson {
+	if src.root != nil {
+		entries, _ := fs.ReadDir(src.root, ".")
+		for _, entry := range entries {
+			switch name := entry.Name(); {
+			case name == "lessons" || name == "funfacts" || name == "courses" || name == "course.json":
+			case strings.HasPrefix(name, ".") || strings.HasPrefix(strings.ToUpper(name), "README"):
+			default:
+				l.warn(src, name, "", "not part of a lesson pack and will be ignored")
+			}
+		}
+	}
+
+	var lessons []lintedLesson
+	for _, file := range l.jsonFiles(src, src.lessonsFS, src.lessonsDir) {
+		var data types.LessonsData
+		if !l.decode(src, src.lessonsFS, file, &data) {
+			continue
+		}
+		for _, lesson := range data.Lessons {
+			lessons = append(lessons, lintedLesson{file: path.Base(file), lesson: lesson})
+		}
+	}
+
+	ids := map[string]string{}
+	for _, item := range lessons {
+		id := item.lesson.ID
+		if id == "" {
+			l.add(src, item.file, item.lesson.Title, "lesson has no id")
+			continue
+		}
+		if first, dup := ids[id]; dup {
+			l.add(src, item.file, id, "duplicate lesson id (also in %s)", first)
+		} else {
+			ids[id] = item.file
+		}
+		if src.pack && l.builtin[id] {
+			l.warn(src, item.file, id, "id is taken by a built-in lesson and will be renamed to %s/%s", strings.TrimPrefix(src.name, "pack "), id)
+		}
+		l.lintLesson(src, item.file, item.lesson)
+	}
+	l.lintPrerequisites(src, lessons, ids)
+
+	facts := map[string]bool{}
+	for _, file := range l.jsonFiles(src, src.factsFS, src.factsDir) {
+		var data types.FunFactsData
+		if !l.decode(src, src.factsFS, file, &data) {
+			continue
+		}
+		for _, fact := range data.Facts {
+			switch {
+			case fact.ID == "":
+				l.add(src, path.Base(file), "", "fun fact %q has no id", fact.Title)
+			case facts[fact.ID]:
+				l.add(src, path.Base(file), "", "duplicate fun fact id %q", fact.ID)
+			}
+			facts[fact.ID] = true
+		}
+	}
+
+	var courseFiles []string
+	if src.coursePath != "" && exists(src.coursesFS, src.coursePath) {
+		courseFiles = append(courseFiles, src.coursePath)
+	}
+	courseFiles = append(courseFiles, l.jsonFiles(src, src.coursesFS, src.coursesDir)...)
+
+	courses := map[string]string{}
+	for _, file := range courseFiles {
+		var course types.CourseData
+		if !l.decode(src, src.coursesFS, file, &course) {
+			continue
+		}
+		id := course.Course.ID
+		if first, dup := courses[id]; dup && id != "" {
+			l.add(src, path.Base(file), "", "duplicate course id %q (also in %s)", id, first)
+		} else {
+			courses[id] = path.Base(file)
+		}
+		if src.pack && l.builtinCourses[id] {
+			l.warn(src, path.Base(file), "", "course id %q is taken by a built-in course and will be renamed to %s/%s", id, strings.TrimPrefix(src.name, "pack "), id)
+		}
+		if !src.pack {
+			l.builtinCourses[id] = true
+		}
+		l.lintCourse(src, path.Base(file), course, ids)
+	}
+
+	return lessons
+}
+
+// jsonFiles lists the JSON files in dir, warning about anything else there
+// since the loader skips it.
+func (l *linter) jsonFiles(src lintSource, fsys fs.FS, dir string) []string {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		if src.pack && os.IsNotExist(err) {
+			return nil
+		}
+		l.add(src, dir, "", "%v", err)
+		return nil
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			l.warn(src, path.Join(path.Base(dir), entry.Name()), "", "not a .json file and will be ignored")
+			continue
+		}
+		files = append(files, path.Join(dir, entry.Name()))
+	}
+	return files
+}
+
+func (l *linter) decode(src lintSource, fsys fs.FS, file string, v any) bool {
+	content, err := fs.ReadFile(fsys, file)
+	if err != nil {
+		l.add(src, path.Base(file), "", "%v", err)
+		return false
+	}
+	dec := json.NewDecoder(bytes.NewReader(content))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		l.add(src, path.Base(file), "", "does not match the schema: %v", err)
+		return false
+	}
+	return true
+}
+
+func (l *linter) lintCourse(src lintSource, file string, course types.CourseData, ids map[string]string) {
+	if course.Course.ID == "" {
+		l.add(src, file, "", "course has no id")
+	}
+	seen := map[string]bool{}
+	for _, ref := range course.Course.Lessons {
+		if seen[ref.LessonId] {
+			l.warn(src, file, ref.LessonId, "lesson is listed in the course more than once")
+		}
+		seen[ref.LessonId] = true
+		if _, ok := ids[ref.LessonId]; !ok && !l.builtin[ref.LessonId] {
+			l.add(src, file, ref.LessonId, "course refers to a lesson that does not exist")
+		}
+	}
+}
+
+// lintPrerequisites checks that every prerequisite names a lesson and that
+// no lesson depends on itself, directly or through others.
+func (l *linter) lintPrerequisites(src lintSource, lessons []lintedLesson, ids map[string]string) {
+	graph := map[string][]string{}
+	files := map[string]string{}
+	for _, item := range lessons {
+		id := item.lesson.ID
+		for _, prereq := range item.lesson.Prerequisites {
+			switch _, ok := ids[prereq]; {
+			case prereq == id:
+				l.add(src, item.file, id, "lesson lists itself as a prerequisite")
+			case !ok && !l.builtin[prereq]:
+				l.add(src, item.file, id, "prerequisite %s does not exist", prereq)
+			case ok:
+				graph[id] = append(graph[id], prereq)
+			}
+		}
+		files[id] = item.file
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := map[string]int{}
+	var path []string
+	var visit func(id string)
+	visit = func(id string) {
+		state[id] = visiting
+		path = append(path, id)
+		for _, prereq := range graph[id] {
+			switch state[prereq] {
+			case visiting:
+				start := slices.Index(path, prereq)
+				cycle := append(slices.Clone(path[start:]), prereq)
+				l.add(src, files[prereq], prereq, "prerequisites form a cycle: %s", strings.Join(cycle, " -> "))
+			case unvisited:
+				visit(prereq)
+			}
+		}
+		path = path[:len(path)-1]
+		state[id] = done
+	}
+	for _, item := range lessons {
+		if state[item.lesson.ID] == unvisited {
+			visit(item.lesson.ID)
+		}
+	}
+}
+
+func (l *linter) lintLesson(src lintSource, file string, lesson types.Lesson) {
+	id := lesson.ID
+
+	if err := lab.CheckValidators(lesson); err != nil {
+		for _, line := range strings.Split(err.Error(), "\n") {
+			l.add(src, file, id, "%s", line)
+		}
+	}
+
+	if err := lab.CheckQuiz(lesson); err != nil {
+		for _, line := range strings.Split(err.Error(), "\n") {
+			l.add(src, file, id, "%s", line)
+		}
+	}
+
+	if lesson.Quiz != nil {
+		if len(lesson.Steps) > 0 || len(lesson.Requirements) > 0 {
+			l.warn(src, file, id, "requirements are never checked because the lesson is a quiz")
+		}
+	} else if len(lesson.Steps) == 0 && len(lesson.Requirements) == 0 {
+		l.add(src, file, id, "lesson has no requirements")
+	}
+	steps := map[string]bool{}
+	for i, step := range lesson.Steps {
+		if len(step.Requirements) == 0 {
+			l.add(src, file, id, "step %d has no requirements", i+1)
+		}
+		stepID := lab.StepID(lesson, i)
+		if steps[stepID] {
+			l.add(src, file, id, "duplicate step id %q", stepID)
+		}
+		steps[stepID] = true
+	}
+
+	if lesson.Solution != "" && !lab.UsesSandbox(lesson) {
+		l.warn(src, file, id, "solution is never run because the lesson has no sandbox")
+	}
+
+	l.lintRequirements(src, file, id, lab.AllRequirements(lesson))
+	if lab.UsesSandbox(lesson) {
+		l.lintSandbox(src, file, id, lesson.Sandbox)
+		l.lintUnusedFiles(src, file, lesson)
+	}
+}
+
+var (
+	listsFiles    = regexp.MustCompile(`\bls\b`)
+	searchesFiles = regexp.MustCompile(`\bfind [./~]|\bgrep -\w*r|\bls -\w*R|\btree\b`)
+)
+
+// lintUnusedFiles warns about sandbox files that nothing in the lesson
+// mentions, by path, by name, by a directory they are in or by a "*.ext"
+// pattern. Files the lesson has the learner list or search the start
+// directory for count as used. Any other file is either left over from an
+// earlier version of the lesson or never pointed out to the learner.
+func (l *linter) lintUnusedFiles(src lintSource, file string, lesson types.Lesson) {
+	texts := append([]string{lesson.Instructions, lesson.Solution}, lesson.Hints...)
+	for _, step := range lesson.Steps {
+		texts = append(texts, step.Instructions)
+		texts = append(texts, step.Hints...)
+	}
+	for _, req := range lab.AllRequirements(lesson) {
+		texts = append(texts, req.Description, req.Expected, req.Path, req.Command, req.Member)
+		texts = append(texts, req.Entries...)
+	}
+	text := strings.Join(texts, "\n")
+
+	lists := listsFiles.MatchString(text)
+	searches := searchesFiles.MatchString(text)
+
+	start := path.Clean(lesson.Sandbox.StartDir)
+	for _, p := range sortedKeys(lesson.Sandbox.Files) {
+		rel := strings.TrimPrefix(path.Clean(p), start+"/")
+		if start == "." {
+			rel = path.Clean(p)
+		}
+		inStart := rel != path.Clean(p) || start == "."
+		if inStart && (searches || lists && !strings.Contains(rel, "/")) {
+			continue
+		}
+
+		mentioned := path.Ext(p) != "" && strings.Contains(text, "*"+path.Ext(p))
+		for q := path.Clean(p); q != "." && q != "/" && !mentioned; q = path.Dir(q) {
+			mentioned = strings.Contains(text, q) || strings.Contains(text, path.Base(q))
+		}
+		if !mentioned {
+			l.warn(src, file, lesson.ID, "sandbox file %s is never mentioned in the instructions, hints or requirements", p)
+		}
+	}
+}
+
+func (l *linter) lintRequirements(src lintSource, file, id string, reqs []types.Requirement) {
+	for _, req := range reqs {
+		switch req.Validator {
+		case "regex", "file_matches", "command_matches_regex":
+			if _, err := regexp.Compile(req.Expected); err != nil {
+				l.add(src, file, id, "invalid regex in %q: %v", req.Description, err)
+			}
+		case "computed":
+			if req.Compute == nil {
+				l.add(src, file, id, "computed requirement %q has no compute rule", req.Description)
+			} else if req.Compute.Pattern != "" {
+				if _, err := regexp.Compile(req.Compute.Pattern); err != nil {
+					l.add(src, file, id, "invalid regex in %q: %v", req.Description, err)
+				}
+			}
+		}
+		if req.Path != "" && lab.EscapesSandbox(req.Path) {
+			l.add(src, file, id, "requirement %q checks %s, outside the sandbox", req.Description, req.Path)
+		}
+	}
+}
+
+func (l *linter) lintSandbox(src lintSource, file, id string, sb types.SandboxConfig) {
+	created := map[string]bool{".": true}
+	create := func(kind, p string) {
+		if lab.EscapesSandbox(p) {
+			l.add(src, file, id, "sandbox %s %s is outside the sandbox", kind, p)
+			return
+		}
+		for p = path.Clean(p); p != "." && p != "/"; p = path.Dir(p) {
+			created[p] = true
+		}
+	}
+
+	for _, dir := range sb.Dirs {
+		create("directory", dir)
+	}
+	for _, p := range sortedKeys(sb.Files) {
+		create("file", p)
+	}
+	for _, p := range sortedKeys(sb.Scripts) {
+		create("script", p)
+	}
+	for _, p := range sortedKeys(sb.Symlinks) {
+		create("symlink", p)
+	}
+	for _, fixture := range sb.Fixtures {
+		create("fixture", fixture.Path)
+	}
+	for _, gen := range sb.Generated {
+		create("generated file", gen.Path)
+	}
+
+	if sb.StartDir != "" {
+		if lab.EscapesSandbox(sb.StartDir) {
+			l.add(src, file, id, "startDir %s is outside the sandbox", sb.StartDir)
+		} else if !created[path.Clean(sb.StartDir)] {
+			l.add(src, file, id, "startDir %s is not created by the sandbox", sb.StartDir)
+		}
+	}
+	for _, p := range sortedKeys(sb.Modes) {
+		if !created[path.Clean(p)] {
+			l.add(src, file, id, "mode set for %s, which the sandbox does not create", p)
+		}
+	}
+	for _, p := range sortedKeys(sb.Mtimes) {
+		if !created[path.Clean(p)] {
+			l.add(src, file, id, "mtime set for %s, which the sandbox does not create", p)
+		}
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,50 @@
+package lessons
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bobparsons/rootcamp/internal/types"
+)
+
+func TestLintUnusedFiles(t *testing.T) {
+	lesson := types.Lesson{
+		ID:           "cat",
+		Instructions: "## Your Task\n\nRead `notes/todo.txt` and find the code.",
+		Hints:        []string{"The code is in the file under docs/"},
+		Sandbox: types.SandboxConfig{
+			StartDir: "work",
+			Files: map[string]string{
+				"notes/todo.txt": "todo",
+				"docs/guide.md":  "guide",
+				"old/stale.txt":  "left over",
+				"work/draft.txt": "not listed",
+			},
+		},
+		Requirements: []types.Requirement{{
+			Type:        "file",
+			Description: "Copy the code to answer.txt",
+		}},
+	}
+
+	l := &linter{}
+	l.lintUnusedFiles(lintSource{name: "test"}, "cat.json", lesson)
+
+	var unused []string
+	for _, issue := range l.issues {
+		if !issue.Warning {
+			t.Errorf("unexpected error: %s", issue)
+		}
+		unused = append(unused, strings.Fields(issue.Message)[2])
+	}
+	if strings.Join(unused, " ") != "old/stale.txt work/draft.txt" {
+		t.Errorf("unused files = %v, want [old/stale.txt work/draft.txt]", unused)
+	}
+
+	lesson.Instructions += "\n\nRun `ls` to see the files you start with."
+	l = &linter{}
+	l.lintUnusedFiles(lintSource{name: "test"}, "cat.json", lesson)
+	if len(l.issues) != 1 || !strings.Contains(l.issues[0].Message, "old/stale.txt") {
+		t.Errorf("with ls, issues = %v, want only old/stale.txt", l.issues)
+	}
+}
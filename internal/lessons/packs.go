@@ -0,0 +1,256 @@
+package lessons
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/bobparsons/rootcamp/internal/types"
+)
+
+// A lesson pack is a directory laid out like the embedded data: lesson
+// files in lessons/, fun facts in funfacts/ and courses in courses/. A single
+// course.json at the top of the pack is read as well. Packs are read from
+// ~/.rootcamp/packs/<name>/ and from any directory passed with --pack, after
+// the built-in lessons. A pack lesson, fact or course whose ID is already
+// taken is renamed to "<pack>/<id>", and the pack's courses and lesson
+// prerequisites follow any renamed lessons.
+type Pack struct {
+	Name    string
+	Dir     string
+	Lessons int
+	Facts   int
+	Courses int
+	Renamed map[string]string
+}
+
+type packData struct {
+	Pack
+	lessons []types.Lesson
+	facts   []types.FunFact
+	courses []types.Course
+}
+
+var (
+	extraPackDirs []string
+	cachedPacks   []packData
+	packErrors    []error
+)
+
+// AddPackDir loads the directory as a lesson pack on top of the installed
+// ones. It must be called before lessons are first loaded.
+func AddPackDir(dir string) {
+	extraPackDirs = append(extraPackDirs, dir)
+}
+
+// PacksDir is where lesson packs are installed, one directory per pack.
+func PacksDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".rootcamp", "packs"), nil
+}
+
+// LoadedPacks describes the packs that loaded, in load order.
+func LoadedPacks() []Pack {
+	LoadLessons()
+	packs := make([]Pack, len(cachedPacks))
+	for i, p := range cachedPacks {
+		packs[i] = p.Pack
+	}
+	return packs
+}
+
+// PackErrors returns why any pack was skipped. One broken pack does not stop
+// the built-in lessons or other packs from loading.
+func PackErrors() []error {
+	LoadLessons()
+	return packErrors
+}
+
+// packDirs lists installed packs in name order, then those added with
+// AddPackDir.
+func packDirs() []string {
+	var dirs []string
+	if root, err := PacksDir(); err == nil {
+		entries, err := os.ReadDir(root)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			packErrors = append(packErrors, fmt.Errorf("failed to read %s: %w", root, err))
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				dirs = append(dirs, filepath.Join(root, entry.Name()))
+			}
+		}
+	}
+	return append(dirs, extraPackDirs...)
+}
+
+// loadPacks reads every pack and returns their lessons, renamed where they
+// clash with the built-in lessons or an earlier pack.
+func loadPacks(builtin []types.Lesson) []types.Lesson {
+	taken := make(map[string]bool)
+	for _, lesson := range builtin {
+		taken[lesson.ID] = true
+	}
+
+	cachedPacks = nil
+	packErrors = nil
+	var lessons []types.Lesson
+	for _, dir := range packDirs() {
+		p, err := readPack(dir)
+		if err != nil {
+			packErrors = append(packErrors, fmt.Errorf("lesson pack %s: %w", dir, err))
+			continue
+		}
+
+		if err := namespaceLessons(&p, taken); err != nil {
+			packErrors = append(packErrors, fmt.Errorf("lesson pack %s: %w", dir, err))
+			continue
+		}
+		for _, lesson := range p.lessons {
+			for i, id := range lesson.Prerequisites {
+				if renamed, ok := p.Renamed[id]; ok {
+					lesson.Prerequisites[i] = renamed
+				}
+			}
+		}
+		for _, course := range p.courses {
+			for i, ref := range course.Lessons {
+				if renamed, ok := p.Renamed[ref.LessonId]; ok {
+					course.Lessons[i].LessonId = renamed
+				}
+			}
+		}
+
+		cachedPacks = append(cachedPacks, p)
+		lessons = append(lessons, p.lessons...)
+	}
+	return lessons
+}
+
+// namespaceLessons renames the pack's lessons whose IDs are taken, and marks
+// the IDs it ends up with as taken. Nothing is marked if the pack cannot be
+// loaded.
+func namespaceLessons(p *packData, taken map[string]bool) error {
+	ids := make(map[string]bool)
+	for i := range p.lessons {
+		id := p.lessons[i].ID
+		if id == "" {
+			return fmt.Errorf("a lesson has no id")
+		}
+		if taken[id] || ids[id] {
+			renamed := p.Name + "/" + id
+			if taken[renamed] || ids[renamed] {
+				return fmt.Errorf("lesson %s clashes with another lesson even as %s", id, renamed)
+			}
+			p.Renamed[id] = renamed
+			p.lessons[i].ID = renamed
+		}
+		ids[p.lessons[i].ID] = true
+	}
+	for id := range ids {
+		taken[id] = true
+	}
+	return nil
+}
+
+func readPack(dir string) (packData, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return packData{}, err
+	}
+	if !info.IsDir() {
+		return packData{}, fmt.Errorf("not a directory")
+	}
+
+	fsys := os.DirFS(dir)
+	p := packData{Pack: Pack{Name: filepath.Base(dir), Dir: dir, Renamed: map[string]string{}}}
+
+	if exists(fsys, "lessons") {
+		if p.lessons, err = readLessons(fsys, "lessons"); err != nil {
+			return packData{}, err
+		}
+	}
+	if exists(fsys, "funfacts") {
+		if p.facts, err = readFacts(fsys, "funfacts"); err != nil {
+			return packData{}, err
+		}
+	}
+	if exists(fsys, "course.json") {
+		courseData, err := readCourse(fsys, "course.json")
+		if err != nil {
+			return packData{}, err
+		}
+		p.courses = append(p.courses, courseData.Course)
+	}
+	if exists(fsys, "courses") {
+		courses, err := readCourses(fsys, "courses")
+		if err != nil {
+			return packData{}, err
+		}
+		p.courses = append(p.courses, courses...)
+	}
+	if len(p.lessons) == 0 && len(p.facts) == 0 && len(p.courses) == 0 {
+		return packData{}, fmt.Errorf("no lessons, fun facts or courses found")
+	}
+
+	p.Lessons = len(p.lessons)
+	p.Facts = len(p.facts)
+	p.Courses = len(p.courses)
+	return p, nil
+}
+
+// packFacts returns the packs' fun facts, renamed where they clash with the
+// built-in facts or an earlier pack.
+func packFacts(builtin []types.FunFact) []types.FunFact {
+	LoadLessons()
+
+	taken := make(map[string]bool)
+	for _, fact := range builtin {
+		taken[fact.ID] = true
+	}
+
+	var facts []types.FunFact
+	for _, p := range cachedPacks {
+		for _, fact := range p.facts {
+			if taken[fact.ID] {
+				fact.ID = p.Name + "/" + fact.ID
+			}
+			taken[fact.ID] = true
+			facts = append(facts, fact)
+		}
+	}
+	return facts
+}
+
+func exists(fsys fs.FS, name string) bool {
+	_, err := fs.Stat(fsys, name)
+	return err == nil
+}
+
+// packCourses returns the packs' courses, renamed where they clash with the
+// built-in courses or an earlier pack.
+func packCourses(builtin []types.Course) []types.Course {
+	LoadLessons()
+
+	taken := make(map[string]bool)
+	for _, course := range builtin {
+		taken[course.ID] = true
+	}
+
+	var courses []types.Course
+	for _, p := range cachedPacks {
+		for _, course := range p.courses {
+			if taken[course.ID] {
+				course.ID = p.Name + "/" + course.ID
+			}
+			taken[course.ID] = true
+			courses = append(courses, course)
+		}
+	}
+	return courses
+}
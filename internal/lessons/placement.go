@@ -0,0 +1,111 @@
+package lessons
+
+import (
+	"math/rand"
+	"sort"
+
+	"github.com/bobparsons/rootcamp/internal/types"
+)
+
+// PlacementChallenges is how many lessons from each tier the placement test
+// asks the learner to solve.
+const PlacementChallenges = 2
+
+// PlacementTier is the lessons of one module at one level. Solving a sample
+// of them in the placement test tests the learner out of the whole tier.
+type PlacementTier struct {
+	Module  string
+	Level   string
+	Lessons []types.Lesson
+}
+
+// LessonIDs returns the IDs of the tier's lessons.
+func (t PlacementTier) LessonIDs() []string {
+	ids := make([]string, len(t.Lessons))
+	for i, lesson := range t.Lessons {
+		ids[i] = lesson.ID
+	}
+	return ids
+}
+
+// Challenges picks up to n of the tier's lessons at random, keeping them in
+// the order they are defined.
+func (t PlacementTier) Challenges(n int) []types.Lesson {
+	picked := rand.Perm(len(t.Lessons))
+	if n < len(picked) {
+		picked = picked[:n]
+	}
+	sort.Ints(picked)
+
+	challenges := make([]types.Lesson, len(picked))
+	for i, idx := range picked {
+		challenges[i] = t.Lessons[idx]
+	}
+	return challenges
+}
+
+// PlacementTiers groups lessons into tiers for the placement test: one list
+// per module, each running from its easiest level to its hardest. Modules
+// that start at an easier level come first, then modules by name. Lessons
+// without a module or level are left out.
+func PlacementTiers(all []types.Lesson) [][]PlacementTier {
+	byModule := make(map[string]map[string][]types.Lesson)
+	for _, lesson := range all {
+		if lesson.Module == "" || lesson.Level == "" {
+			continue
+		}
+		if byModule[lesson.Module] == nil {
+			byModule[lesson.Module] = make(map[string][]types.Lesson)
+		}
+		byModule[lesson.Module][lesson.Level] = append(byModule[lesson.Module][lesson.Level], lesson)
+	}
+
+	tiers := make([][]PlacementTier, 0, len(byModule))
+	for module, levelLessons := range byModule {
+		levels := make([]string, 0, len(levelLessons))
+		for level := range levelLessons {
+			levels = append(levels, level)
+		}
+		sort.Slice(levels, func(i, j int) bool {
+			return levelLess(levels[i], levels[j])
+		})
+
+		moduleTiers := make([]PlacementTier, len(levels))
+		for i, level := range levels {
+			moduleTiers[i] = PlacementTier{Module: module, Level: level, Lessons: levelLessons[level]}
+		}
+		tiers = append(tiers, moduleTiers)
+	}
+
+	sort.Slice(tiers, func(i, j int) bool {
+		a, b := tiers[i][0], tiers[j][0]
+		if a.Level != b.Level {
+			return levelLess(a.Level, b.Level)
+		}
+		return a.Module < b.Module
+	})
+	return tiers
+}
+
+// levelLess orders the levels used in lesson data from easiest to hardest.
+// Unknown levels sort after the known ones, by name.
+func levelLess(a, b string) bool {
+	if ra, rb := levelRank(a), levelRank(b); ra != rb {
+		return ra < rb
+	}
+	return a < b
+}
+
+func levelRank(level string) int {
+	switch level {
+	case "beginner":
+		return 1
+	case "intermediate":
+		return 2
+	case "advanced":
+		return 3
+	case "expert":
+		return 4
+	}
+	return 5
+}
@@ -0,0 +1,267 @@
+package lessons
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bobparsons/rootcamp/internal/lab"
+	"github.com/bobparsons/rootcamp/internal/types"
+)
+
+// ReviewSessionSize is the most cards served in one review session.
+const ReviewSessionSize = 10
+
+const (
+	initialEase = 2.5
+	minimumEase = 1.3
+)
+
+// Review grades, on the SM-2 scale of 0 to 5. Anything below ReviewPass
+// sends the card back to the start of its schedule.
+const (
+	ReviewFailed  = 1
+	ReviewPass    = 3
+	ReviewGood    = 4
+	ReviewPerfect = 5
+)
+
+// ReviewCards derives command-recall cards from the lesson's example and
+// hints. In the example, a comment line directly above a command becomes the
+// card's prompt. The first hint written as "label: command" also becomes a
+// card, prompted by the lesson's title, since hint labels tend to give the
+// answer away. Either way the command must run the program the lesson
+// teaches.
+func ReviewCards(lesson types.Lesson) []types.ReviewCard {
+	var cards []types.ReviewCard
+	seen := make(map[string]bool)
+	add := func(prompt, command string) bool {
+		if !usablePrompt(prompt) || !drillable(command, lesson.Command) || seen[command] {
+			return false
+		}
+		seen[command] = true
+		cards = append(cards, types.ReviewCard{
+			ID:       lesson.ID + ":" + command,
+			LessonID: lesson.ID,
+			Prompt:   strings.TrimSuffix(prompt, ":"),
+			Command:  command,
+			Cloze:    cloze(command, lesson.Command),
+		})
+		return true
+	}
+
+	// A comment straight after a command is about that command, such as
+	// its output, so it can't be the prompt for the next one.
+	prompt, afterCommand := "", false
+	for _, line := range strings.Split(lesson.About.Example, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "```") {
+			prompt, afterCommand = "", false
+			continue
+		}
+		if strings.HasPrefix(line, "#") && !strings.HasPrefix(line, "#!") {
+			if !afterCommand {
+				prompt = strings.TrimSpace(strings.TrimLeft(line, "#"))
+			}
+			continue
+		}
+		add(prompt, stripComment(strings.TrimPrefix(line, "$ ")))
+		prompt, afterCommand = "", line != ""
+	}
+
+	for _, hint := range lesson.Hints {
+		_, command, ok := strings.Cut(hint, ": ")
+		if ok && !strings.Contains(command, " or ") && !strings.Contains(command, "(") &&
+			add(lesson.Title, stripComment(strings.Trim(command, "`"))) {
+			break
+		}
+	}
+	return cards
+}
+
+// drillable reports whether the command runs the program somewhere on the
+// line and is complete enough to be typed as it is. Continued lines and
+// templates such as "command | wc -l" are left out.
+func drillable(command, program string) bool {
+	return command != "" && !strings.HasSuffix(command, "\\") &&
+		lab.CommandName(command) != "" && lab.RunsProgram(command, program)
+}
+
+// usablePrompt leaves out comments that aren't a description of the command
+// below them, such as "...", "OR" or "or", and warnings against running it.
+func usablePrompt(prompt string) bool {
+	if len(prompt) < 3 || strings.HasPrefix(prompt, ".") || strings.ToUpper(prompt) == prompt {
+		return false
+	}
+	lower := strings.ToLower(prompt)
+	for _, warning := range []string{"don't", "never", "avoid", "bad", "wrong", "❌"} {
+		if strings.Contains(lower, warning) {
+			return false
+		}
+	}
+	return true
+}
+
+// stripComment removes a trailing "# comment" that isn't inside quotes.
+func stripComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '#' && i > 0 && (line[i-1] == ' ' || line[i-1] == '\t'):
+			return strings.TrimSpace(line[:i])
+		}
+	}
+	return strings.TrimSpace(line)
+}
+
+// cloze blanks out the program and its flags wherever the command runs it,
+// leaving the operands and the rest of the pipeline as a clue.
+func cloze(command, program string) string {
+	words := strings.Fields(command)
+	blanked := false
+	for i, word := range words {
+		if strings.ContainsAny(word, "|;&") {
+			blanked = false
+			continue
+		}
+		if word == program || strings.HasSuffix(word, "/"+program) {
+			words[i] = types.QuizBlank
+			blanked = true
+		} else if blanked && len(word) > 1 && strings.HasPrefix(word, "-") {
+			words[i] = types.QuizBlank
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// DrillLesson turns a card into a lesson worked in the lesson's sandbox. It
+// is passed once the learner runs a command equivalent to the card's.
+func DrillLesson(lesson types.Lesson, card types.ReviewCard) types.Lesson {
+	return types.Lesson{
+		ID:      lesson.ID,
+		Command: lesson.Command,
+		Code:    lesson.Code,
+		Title:   "Review: " + lesson.Title,
+		Level:   lesson.Level,
+		Module:  lesson.Module,
+		Hints:   lesson.Hints,
+		Sandbox: lesson.Sandbox,
+		Instructions: "## Your Task\n\n**" + card.Prompt + "**\n\n" +
+			"Run the command that does this. Flags can be given in any order.\n\n" +
+			"```bash\n" + card.Cloze + "\n```",
+		Requirements: []types.Requirement{{
+			Type:        lab.HistoryRequirement,
+			Description: "Run the command",
+			Validator:   "command_equivalent",
+			Expected:    card.Command,
+		}},
+	}
+}
+
+// NewReviewState is the schedule of a card that has not been reviewed yet,
+// due once it has been a day since its lesson was completed.
+func NewReviewState(card types.ReviewCard, completedAt time.Time) *types.ReviewState {
+	return &types.ReviewState{
+		CardID:   card.ID,
+		LessonID: card.LessonID,
+		Ease:     initialEase,
+		DueAt:    completedAt.Add(24 * time.Hour),
+	}
+}
+
+// NextReview reschedules the card after a review graded from 0 to 5, using
+// the SM-2 algorithm. A failed card is seen again the next day; a passed one
+// after 1 day, then 6, then the last interval times its ease.
+func NextReview(state types.ReviewState, quality int, now time.Time) types.ReviewState {
+	if quality < ReviewPass {
+		state.Repetitions = 0
+		state.IntervalDays = 1
+	} else {
+		switch state.Repetitions {
+		case 0:
+			state.IntervalDays = 1
+		case 1:
+			state.IntervalDays = 6
+		default:
+			state.IntervalDays = int(math.Round(float64(state.IntervalDays) * state.Ease))
+		}
+		state.Repetitions++
+	}
+
+	miss := float64(5 - quality)
+	state.Ease = math.Max(minimumEase, state.Ease+0.1-miss*(0.08+miss*0.02))
+	state.DueAt = now.AddDate(0, 0, state.IntervalDays)
+	state.LastReviewedAt = &now
+	return state
+}
+
+// ReviewQuality grades a drill from the number of commands the learner ran
+// before the one that matched, or ReviewFailed if none did.
+func ReviewQuality(misses int, matched bool) int {
+	switch {
+	case !matched:
+		return ReviewFailed
+	case misses == 0:
+		return ReviewPerfect
+	case misses <= 2:
+		return ReviewGood
+	}
+	return ReviewPass
+}
+
+// ReviewItem is a card with the lesson it came from and its schedule.
+type ReviewItem struct {
+	Lesson types.Lesson
+	Card   types.ReviewCard
+	State  types.ReviewState
+}
+
+// ScheduledReviews lists the cards of every completed lesson, soonest due
+// first. Cards without a saved state are scheduled from the lesson's
+// completion. Lessons that were tested out of are left out, as the learner
+// never worked through them.
+func ScheduledReviews(all []types.Lesson, progressMap map[string]*types.UserProgress, states map[string]*types.ReviewState) []ReviewItem {
+	var items []ReviewItem
+	for _, lesson := range all {
+		progress, ok := progressMap[lesson.ID]
+		if !ok || !progress.Completed {
+			continue
+		}
+		completedAt := time.Now()
+		if progress.CompletedAt != nil {
+			completedAt = *progress.CompletedAt
+		}
+
+		for _, card := range ReviewCards(lesson) {
+			state, ok := states[card.ID]
+			if !ok {
+				state = NewReviewState(card, completedAt)
+			}
+			items = append(items, ReviewItem{Lesson: lesson, Card: card, State: *state})
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].State.DueAt.Before(items[j].State.DueAt)
+	})
+	return items
+}
+
+// DueReviews returns the items due by now, up to ReviewSessionSize of them.
+func DueReviews(items []ReviewItem, now time.Time) []ReviewItem {
+	var due []ReviewItem
+	for _, item := range items {
+		if item.State.DueAt.After(now) || len(due) == ReviewSessionSize {
+			break
+		}
+		due = append(due, item)
+	}
+	return due
+}
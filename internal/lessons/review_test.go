@@ -0,0 +1,39 @@
+package lessons
+
+import (
+	"testing"
+
+	"github.com/bobparsons/rootcamp/internal/types"
+)
+
+func TestReviewCardsFromHints(t *testing.T) {
+	lesson := types.Lesson{
+		ID:      "uniq-c",
+		Command: "uniq",
+		Title:   "Count Occurrences with Uniq",
+		About: types.LessonAbout{
+			Example: "```bash\nsort data.txt\n# Output:\n#    apple\n```",
+		},
+		Hints: []string{
+			"Use -c flag to count occurrences",
+			"Syntax: command | uniq -c",
+			"Pattern: sort filename | uniq -c",
+			"For top frequencies: cut -d ' ' -f 1 access.log | sort | uniq -c | sort -rn | head",
+		},
+	}
+
+	cards := ReviewCards(lesson)
+	if len(cards) != 1 {
+		t.Fatalf("got %d cards, want 1: %+v", len(cards), cards)
+	}
+	card := cards[0]
+	if card.Prompt != lesson.Title {
+		t.Errorf("prompt = %q, want %q", card.Prompt, lesson.Title)
+	}
+	if card.Command != "sort filename | uniq -c" {
+		t.Errorf("command = %q", card.Command)
+	}
+	if card.Cloze != "sort filename | ___ ___" {
+		t.Errorf("cloze = %q", card.Cloze)
+	}
+}
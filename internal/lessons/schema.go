@@ -0,0 +1,22 @@
+package lessons
+
+import (
+	"embed"
+	"fmt"
+)
+
+//go:embed schema/*.schema.json
+var schemaFS embed.FS
+
+// SchemaNames lists the published JSON Schemas, one for each kind of file
+// that makes up a course or lesson pack.
+var SchemaNames = []string{"lessons", "course", "funfacts"}
+
+// Schema returns the JSON Schema for lesson, course or fun fact files.
+func Schema(name string) ([]byte, error) {
+	content, err := schemaFS.ReadFile("schema/" + name + ".schema.json")
+	if err != nil {
+		return nil, fmt.Errorf("no schema named %q", name)
+	}
+	return content, nil
+}
@@ -9,6 +9,7 @@ import (
 type ProgressStats struct {
 	Total      int
 	Completed  int
+	TestedOut  int
 	Percentage float64
 }
 
@@ -37,18 +38,27 @@ func CalculateProgress(
 	moduleMap := make(map[string]*ProgressStats)
 
 	for _, lesson := range lessons {
-		if prog, exists := progressMap[lesson.ID]; exists && prog.Completed {
+		prog, exists := progressMap[lesson.ID]
+		testedOut := exists && prog.TestedOut && !prog.Completed
+
+		if exists && prog.Completed {
 			overall.Completed++
 		}
+		if testedOut {
+			overall.TestedOut++
+		}
 
 		if lesson.Level != "" {
 			if _, exists := levelMap[lesson.Level]; !exists {
 				levelMap[lesson.Level] = &ProgressStats{}
 			}
 			levelMap[lesson.Level].Total++
-			if prog, exists := progressMap[lesson.ID]; exists && prog.Completed {
+			if exists && prog.Completed {
 				levelMap[lesson.Level].Completed++
 			}
+			if testedOut {
+				levelMap[lesson.Level].TestedOut++
+			}
 		}
 
 		if lesson.Module != "" {
@@ -56,9 +66,12 @@ func CalculateProgress(
 				moduleMap[lesson.Module] = &ProgressStats{}
 			}
 			moduleMap[lesson.Module].Total++
-			if prog, exists := progressMap[lesson.ID]; exists && prog.Completed {
+			if exists && prog.Completed {
 				moduleMap[lesson.Module].Completed++
 			}
+			if testedOut {
+				moduleMap[lesson.Module].TestedOut++
+			}
 		}
 	}
 
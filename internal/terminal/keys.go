@@ -0,0 +1,74 @@
+package terminal
+
+import tea "github.com/charmbracelet/bubbletea"
+
+var keySequences = map[tea.KeyType]string{
+	tea.KeySpace:      " ",
+	tea.KeyShiftTab:   "\x1b[Z",
+	tea.KeyHome:       "\x1b[H",
+	tea.KeyEnd:        "\x1b[F",
+	tea.KeyPgUp:       "\x1b[5~",
+	tea.KeyPgDown:     "\x1b[6~",
+	tea.KeyDelete:     "\x1b[3~",
+	tea.KeyInsert:     "\x1b[2~",
+	tea.KeyCtrlUp:     "\x1b[1;5A",
+	tea.KeyCtrlDown:   "\x1b[1;5B",
+	tea.KeyCtrlRight:  "\x1b[1;5C",
+	tea.KeyCtrlLeft:   "\x1b[1;5D",
+	tea.KeyCtrlHome:   "\x1b[1;5H",
+	tea.KeyCtrlEnd:    "\x1b[1;5F",
+	tea.KeyShiftUp:    "\x1b[1;2A",
+	tea.KeyShiftDown:  "\x1b[1;2B",
+	tea.KeyShiftRight: "\x1b[1;2C",
+	tea.KeyShiftLeft:  "\x1b[1;2D",
+	tea.KeyShiftHome:  "\x1b[1;2H",
+	tea.KeyShiftEnd:   "\x1b[1;2F",
+	tea.KeyF1:         "\x1bOP",
+	tea.KeyF2:         "\x1bOQ",
+	tea.KeyF3:         "\x1bOR",
+	tea.KeyF4:         "\x1bOS",
+	tea.KeyF5:         "\x1b[15~",
+	tea.KeyF6:         "\x1b[17~",
+	tea.KeyF7:         "\x1b[18~",
+	tea.KeyF8:         "\x1b[19~",
+	tea.KeyF9:         "\x1b[20~",
+	tea.KeyF10:        "\x1b[21~",
+	tea.KeyF11:        "\x1b[23~",
+	tea.KeyF12:        "\x1b[24~",
+}
+
+var cursorKeys = map[tea.KeyType]byte{
+	tea.KeyUp:    'A',
+	tea.KeyDown:  'B',
+	tea.KeyRight: 'C',
+	tea.KeyLeft:  'D',
+}
+
+// keyBytes translates a Bubble Tea key event into the bytes a terminal would
+// send to the program for the same keystroke.
+func keyBytes(msg tea.KeyMsg, appCursor bool) []byte {
+	var out []byte
+	if msg.Alt {
+		out = append(out, 0x1b)
+	}
+
+	switch {
+	case msg.Type == tea.KeyRunes:
+		return append(out, string(msg.Runes)...)
+	case msg.Type >= 0:
+		return append(out, byte(msg.Type))
+	}
+
+	if final, ok := cursorKeys[msg.Type]; ok {
+		if appCursor {
+			return append(out, 0x1b, 'O', final)
+		}
+		return append(out, 0x1b, '[', final)
+	}
+
+	if seq, ok := keySequences[msg.Type]; ok {
+		return append(out, seq...)
+	}
+
+	return nil
+}
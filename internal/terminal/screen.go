@@ -0,0 +1,919 @@
+package terminal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/mattn/go-runewidth"
+)
+
+const defaultScrollback = 1000
+
+const (
+	attrBold uint8 = 1 << iota
+	attrDim
+	attrItalic
+	attrUnderline
+	attrBlink
+	attrReverse
+	attrHidden
+	attrStrike
+)
+
+// Colors are stored as -1 for the terminal default, 0-255 for the indexed
+// palette, or colorRGB|0xRRGGBB for true color.
+const (
+	colorDefault = -1
+	colorRGB     = 1 << 24
+)
+
+type style struct {
+	fg    int32
+	bg    int32
+	attrs uint8
+}
+
+var defaultStyle = style{fg: colorDefault, bg: colorDefault}
+
+type cell struct {
+	r     rune
+	style style
+	// cont marks the right half of a double-width rune.
+	cont bool
+}
+
+type parserState int
+
+const (
+	stateGround parserState = iota
+	stateEscape
+	stateEscapeSkip
+	stateCSI
+	stateString
+	stateStringEscape
+)
+
+// Screen is a VT100/xterm compatible screen buffer. It understands the
+// subset of escape sequences that interactive shells and common tools like
+// less, top and vim rely on, and keeps a scrollback of lines that scrolled
+// off the top of the main screen.
+type Screen struct {
+	mu sync.Mutex
+
+	width  int
+	height int
+
+	lines      [][]cell
+	mainLines  [][]cell
+	scrollback [][]cell
+	maxBack    int
+	altActive  bool
+
+	cx, cy   int
+	wrapNext bool
+	style    style
+
+	savedX, savedY int
+	savedStyle     style
+
+	top, bottom   int
+	autowrap      bool
+	cursorVisible bool
+	appCursor     bool
+
+	state   parserState
+	params  []byte
+	pending []byte
+
+	respond func([]byte)
+}
+
+// NewScreen returns a blank screen of the given size. respond, if non-nil,
+// receives replies to device status queries so they can be written back to
+// the program.
+func NewScreen(width, height int, respond func([]byte)) *Screen {
+	width = max(width, 1)
+	height = max(height, 1)
+
+	s := &Screen{
+		width:         width,
+		height:        height,
+		maxBack:       defaultScrollback,
+		autowrap:      true,
+		cursorVisible: true,
+		style:         defaultStyle,
+		respond:       respond,
+	}
+	s.lines = s.blankLines(height)
+	s.bottom = height - 1
+	return s
+}
+
+func (s *Screen) blankLine() []cell {
+	line := make([]cell, s.width)
+	for i := range line {
+		line[i] = cell{r: ' ', style: s.style}
+	}
+	return line
+}
+
+func (s *Screen) blankLines(n int) [][]cell {
+	lines := make([][]cell, n)
+	for i := range lines {
+		lines[i] = s.blankLine()
+	}
+	return lines
+}
+
+func (s *Screen) Size() (int, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.width, s.height
+}
+
+func (s *Screen) ScrollbackLen() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.scrollback)
+}
+
+func (s *Screen) AppCursorKeys() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.appCursor
+}
+
+// Resize changes the screen dimensions. Rows that no longer fit above the
+// cursor are pushed into scrollback so the prompt stays visible.
+func (s *Screen) Resize(width, height int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	width = max(width, 1)
+	height = max(height, 1)
+	if width == s.width && height == s.height {
+		return
+	}
+
+	resizeLines := func(lines [][]cell) [][]cell {
+		for i, line := range lines {
+			switch {
+			case len(line) > width:
+				if line[width].cont {
+					line[width-1] = cell{r: ' ', style: line[width-1].style}
+				}
+				lines[i] = line[:width]
+			case len(line) < width:
+				for len(lines[i]) < width {
+					lines[i] = append(lines[i], cell{r: ' ', style: defaultStyle})
+				}
+			}
+		}
+		return lines
+	}
+
+	s.lines = resizeLines(s.lines)
+	if s.mainLines != nil {
+		s.mainLines = resizeLines(s.mainLines)
+	}
+	s.width = width
+
+	if height < s.height {
+		if shift := s.cy - height + 1; shift > 0 {
+			if !s.altActive {
+				s.pushScrollback(s.lines[:shift]...)
+			}
+			s.lines = s.lines[shift:]
+			s.cy -= shift
+		}
+		s.lines = s.lines[:height]
+		if s.mainLines != nil && len(s.mainLines) > height {
+			s.mainLines = s.mainLines[len(s.mainLines)-height:]
+		}
+	} else {
+		for len(s.lines) < height {
+			s.lines = append(s.lines, s.blankLine())
+		}
+		for s.mainLines != nil && len(s.mainLines) < height {
+			s.mainLines = append(s.mainLines, s.blankLine())
+		}
+	}
+
+	s.height = height
+	s.top = 0
+	s.bottom = height - 1
+	s.cx = min(s.cx, width-1)
+	s.cy = min(s.cy, height-1)
+	s.wrapNext = false
+}
+
+func (s *Screen) pushScrollback(lines ...[]cell) {
+	s.scrollback = append(s.scrollback, lines...)
+	if over := len(s.scrollback) - s.maxBack; over > 0 {
+		s.scrollback = s.scrollback[over:]
+	}
+}
+
+func (s *Screen) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := p
+	if len(s.pending) > 0 {
+		data = append(s.pending, p...)
+		s.pending = nil
+	}
+
+	for len(data) > 0 {
+		b := data[0]
+		if s.state == stateGround && b >= 0x80 {
+			if !utf8.FullRune(data) {
+				s.pending = append([]byte(nil), data...)
+				break
+			}
+			r, size := utf8.DecodeRune(data)
+			s.put(r)
+			data = data[size:]
+			continue
+		}
+		s.feed(b)
+		data = data[1:]
+	}
+
+	return len(p), nil
+}
+
+func (s *Screen) feed(b byte) {
+	switch s.state {
+	case stateEscape:
+		s.escape(b)
+		return
+	case stateEscapeSkip:
+		s.state = stateGround
+		return
+	case stateCSI:
+		switch {
+		case b >= 0x40 && b <= 0x7e:
+			s.csi(b)
+			s.state = stateGround
+		case b == 0x18 || b == 0x1a:
+			s.state = stateGround
+		case b == 0x1b:
+			s.state = stateEscape
+		case b >= 0x20:
+			s.params = append(s.params, b)
+		default:
+			s.control(b)
+		}
+		return
+	case stateString:
+		switch b {
+		case 0x07:
+			s.state = stateGround
+		case 0x1b:
+			s.state = stateStringEscape
+		}
+		return
+	case stateStringEscape:
+		if b == '\\' {
+			s.state = stateGround
+		} else {
+			s.state = stateString
+		}
+		return
+	}
+
+	if b < 0x20 || b == 0x7f {
+		s.control(b)
+		return
+	}
+	s.put(rune(b))
+}
+
+func (s *Screen) control(b byte) {
+	switch b {
+	case 0x1b:
+		s.state = stateEscape
+	case '\a':
+	case '\b':
+		s.wrapNext = false
+		if s.cx > 0 {
+			s.cx--
+		}
+	case '\t':
+		s.wrapNext = false
+		s.cx = min((s.cx/8+1)*8, s.width-1)
+	case '\n', '\v', '\f':
+		s.wrapNext = false
+		s.index()
+	case '\r':
+		s.wrapNext = false
+		s.cx = 0
+	}
+}
+
+func (s *Screen) escape(b byte) {
+	s.state = stateGround
+	switch b {
+	case '[':
+		s.params = s.params[:0]
+		s.state = stateCSI
+	case ']', 'P', '_', '^', 'X':
+		s.state = stateString
+	case '(', ')', '*', '+', '#', '%', ' ':
+		s.state = stateEscapeSkip
+	case '7':
+		s.saveCursor()
+	case '8':
+		s.restoreCursor()
+	case 'D':
+		s.index()
+	case 'E':
+		s.cx = 0
+		s.index()
+	case 'M':
+		s.reverseIndex()
+	case 'c':
+		s.reset()
+	}
+}
+
+func (s *Screen) reset() {
+	s.style = defaultStyle
+	s.altActive = false
+	s.mainLines = nil
+	s.lines = s.blankLines(s.height)
+	s.cx, s.cy = 0, 0
+	s.top, s.bottom = 0, s.height-1
+	s.autowrap = true
+	s.cursorVisible = true
+	s.appCursor = false
+	s.wrapNext = false
+}
+
+func (s *Screen) saveCursor() {
+	s.savedX, s.savedY, s.savedStyle = s.cx, s.cy, s.style
+}
+
+func (s *Screen) restoreCursor() {
+	s.cx = min(s.savedX, s.width-1)
+	s.cy = min(s.savedY, s.height-1)
+	s.style = s.savedStyle
+	s.wrapNext = false
+}
+
+func (s *Screen) put(r rune) {
+	w := runewidth.RuneWidth(r)
+	if w == 0 {
+		return
+	}
+
+	if s.wrapNext {
+		s.wrapNext = false
+		if s.autowrap {
+			s.cx = 0
+			s.index()
+		}
+	}
+
+	if s.cx+w > s.width {
+		if !s.autowrap || w > s.width {
+			return
+		}
+		s.lines[s.cy][s.cx] = cell{r: ' ', style: s.style}
+		s.cx = 0
+		s.index()
+	}
+
+	line := s.lines[s.cy]
+	line[s.cx] = cell{r: r, style: s.style}
+	if w == 2 {
+		line[s.cx+1] = cell{r: ' ', style: s.style, cont: true}
+	}
+
+	s.cx += w
+	if s.cx >= s.width {
+		s.cx = s.width - 1
+		s.wrapNext = true
+	}
+}
+
+func (s *Screen) index() {
+	if s.cy == s.bottom {
+		s.scrollUp(1)
+		return
+	}
+	if s.cy < s.height-1 {
+		s.cy++
+	}
+}
+
+func (s *Screen) reverseIndex() {
+	if s.cy == s.top {
+		s.scrollDown(1)
+		return
+	}
+	if s.cy > 0 {
+		s.cy--
+	}
+}
+
+func (s *Screen) scrollUp(n int) {
+	s.scrollRegionUp(s.top, n, s.top == 0 && !s.altActive)
+}
+
+func (s *Screen) scrollDown(n int) {
+	s.scrollRegionDown(s.top, n)
+}
+
+// scrollRegionUp moves rows top..bottom up by n, optionally keeping the rows
+// that fall off in scrollback.
+func (s *Screen) scrollRegionUp(top, n int, keep bool) {
+	n = min(n, s.bottom-top+1)
+	if keep {
+		s.pushScrollback(s.lines[top : top+n]...)
+	}
+	region := s.lines[top : s.bottom+1]
+	copy(region, region[n:])
+	for i := len(region) - n; i < len(region); i++ {
+		region[i] = s.blankLine()
+	}
+}
+
+func (s *Screen) scrollRegionDown(top, n int) {
+	n = min(n, s.bottom-top+1)
+	region := s.lines[top : s.bottom+1]
+	copy(region[n:], region[:len(region)-n])
+	for i := range n {
+		region[i] = s.blankLine()
+	}
+}
+
+func (s *Screen) csiParams() (string, []int) {
+	raw := string(s.params)
+	private := ""
+	if raw != "" && strings.ContainsRune("?<=>", rune(raw[0])) {
+		private = raw[:1]
+		raw = raw[1:]
+	}
+	raw = strings.TrimRight(raw, " !\"#$%&'()*+,-./")
+
+	var params []int
+	if raw == "" {
+		return private, params
+	}
+	for _, part := range strings.Split(raw, ";") {
+		if idx := strings.IndexByte(part, ':'); idx >= 0 {
+			part = part[:idx]
+		}
+		n, _ := strconv.Atoi(part)
+		params = append(params, min(max(n, 0), maxParam))
+	}
+	return private, params
+}
+
+// maxParam caps CSI parameters, as xterm does, so counts added to the
+// cursor position cannot overflow. Negative parameters, which no terminal
+// defines, are read as 0 and so take the default.
+const maxParam = 65535
+
+func param(params []int, idx, def int) int {
+	if idx >= len(params) || params[idx] <= 0 {
+		return def
+	}
+	return params[idx]
+}
+
+func (s *Screen) csi(final byte) {
+	private, params := s.csiParams()
+	if strings.ContainsAny(string(s.params), " !\"$'") && final != 'm' {
+		return
+	}
+	defer s.clampCursor()
+
+	switch final {
+	case 'A':
+		s.cy = max(s.cy-param(params, 0, 1), s.topBound())
+		s.wrapNext = false
+	case 'B', 'e':
+		s.cy = min(s.cy+param(params, 0, 1), s.bottomBound())
+		s.wrapNext = false
+	case 'C', 'a':
+		s.cx = min(s.cx+param(params, 0, 1), s.width-1)
+		s.wrapNext = false
+	case 'D':
+		s.cx = max(s.cx-param(params, 0, 1), 0)
+		s.wrapNext = false
+	case 'E':
+		s.cy = min(s.cy+param(params, 0, 1), s.bottomBound())
+		s.cx = 0
+		s.wrapNext = false
+	case 'F':
+		s.cy = max(s.cy-param(params, 0, 1), s.topBound())
+		s.cx = 0
+		s.wrapNext = false
+	case 'G', '`':
+		s.cx = min(max(param(params, 0, 1)-1, 0), s.width-1)
+		s.wrapNext = false
+	case 'H', 'f':
+		s.cy = min(max(param(params, 0, 1)-1, 0), s.height-1)
+		s.cx = min(max(param(params, 1, 1)-1, 0), s.width-1)
+		s.wrapNext = false
+	case 'd':
+		s.cy = min(max(param(params, 0, 1)-1, 0), s.height-1)
+		s.wrapNext = false
+	case 'J':
+		s.eraseDisplay(param(params, 0, 0))
+	case 'K':
+		s.eraseLine(param(params, 0, 0))
+	case 'L':
+		if s.cy >= s.top && s.cy <= s.bottom {
+			s.scrollRegionDown(s.cy, param(params, 0, 1))
+			s.cx = 0
+		}
+	case 'M':
+		if s.cy >= s.top && s.cy <= s.bottom {
+			s.scrollRegionUp(s.cy, param(params, 0, 1), false)
+			s.cx = 0
+		}
+	case 'P':
+		n := min(param(params, 0, 1), s.width-s.cx)
+		line := s.lines[s.cy]
+		copy(line[s.cx:], line[s.cx+n:])
+		for i := s.width - n; i < s.width; i++ {
+			line[i] = cell{r: ' ', style: s.style}
+		}
+		s.wrapNext = false
+	case '@':
+		n := min(param(params, 0, 1), s.width-s.cx)
+		line := s.lines[s.cy]
+		copy(line[s.cx+n:], line[s.cx:s.width-n])
+		for i := s.cx; i < s.cx+n; i++ {
+			line[i] = cell{r: ' ', style: s.style}
+		}
+		s.wrapNext = false
+	case 'X':
+		n := min(param(params, 0, 1), s.width-s.cx)
+		for i := s.cx; i < s.cx+n; i++ {
+			s.lines[s.cy][i] = cell{r: ' ', style: s.style}
+		}
+		s.wrapNext = false
+	case 'S':
+		if private == "" {
+			s.scrollUp(param(params, 0, 1))
+		}
+	case 'T':
+		if private == "" {
+			s.scrollDown(param(params, 0, 1))
+		}
+	case 'r':
+		if private != "" {
+			return
+		}
+		top := param(params, 0, 1) - 1
+		bottom := param(params, 1, s.height) - 1
+		if top < bottom && bottom < s.height {
+			s.top, s.bottom = top, bottom
+			s.cx, s.cy = 0, 0
+			s.wrapNext = false
+		}
+	case 'm':
+		if private == "" {
+			s.sgr(params)
+		}
+	case 'h', 'l':
+		s.setModes(private, params, final == 'h')
+	case 's':
+		if private == "" {
+			s.saveCursor()
+		}
+	case 'u':
+		if private == "" {
+			s.restoreCursor()
+		}
+	case 'n':
+		if private != "" || s.respond == nil {
+			return
+		}
+		switch param(params, 0, 0) {
+		case 5:
+			s.respond([]byte("\x1b[0n"))
+		case 6:
+			s.respond(fmt.Appendf(nil, "\x1b[%d;%dR", s.cy+1, s.cx+1))
+		}
+	case 'c':
+		if private == "" && s.respond != nil {
+			s.respond([]byte("\x1b[?1;2c"))
+		}
+	}
+}
+
+// clampCursor keeps the cursor on the screen whatever a sequence asked for.
+func (s *Screen) clampCursor() {
+	s.cx = min(max(s.cx, 0), s.width-1)
+	s.cy = min(max(s.cy, 0), s.height-1)
+}
+
+func (s *Screen) topBound() int {
+	if s.cy >= s.top {
+		return s.top
+	}
+	return 0
+}
+
+func (s *Screen) bottomBound() int {
+	if s.cy <= s.bottom {
+		return s.bottom
+	}
+	return s.height - 1
+}
+
+func (s *Screen) eraseDisplay(mode int) {
+	switch mode {
+	case 0:
+		s.eraseLine(0)
+		for y := s.cy + 1; y < s.height; y++ {
+			s.lines[y] = s.blankLine()
+		}
+	case 1:
+		s.eraseLine(1)
+		for y := 0; y < s.cy; y++ {
+			s.lines[y] = s.blankLine()
+		}
+	case 2:
+		for y := range s.lines {
+			s.lines[y] = s.blankLine()
+		}
+	case 3:
+		s.scrollback = nil
+	}
+	s.wrapNext = false
+}
+
+func (s *Screen) eraseLine(mode int) {
+	line := s.lines[s.cy]
+	from, to := 0, s.width
+	switch mode {
+	case 0:
+		from = s.cx
+	case 1:
+		to = s.cx + 1
+	}
+	for i := from; i < to; i++ {
+		line[i] = cell{r: ' ', style: s.style}
+	}
+	s.wrapNext = false
+}
+
+func (s *Screen) setModes(private string, params []int, on bool) {
+	if private != "?" {
+		return
+	}
+	for _, p := range params {
+		switch p {
+		case 1:
+			s.appCursor = on
+		case 7:
+			s.autowrap = on
+		case 25:
+			s.cursorVisible = on
+		case 47, 1047, 1049:
+			s.setAltScreen(on, p == 1049)
+		case 1048:
+			if on {
+				s.saveCursor()
+			} else {
+				s.restoreCursor()
+			}
+		}
+	}
+}
+
+func (s *Screen) setAltScreen(on, saveCursor bool) {
+	if on == s.altActive {
+		return
+	}
+	if on {
+		if saveCursor {
+			s.saveCursor()
+		}
+		s.mainLines = s.lines
+		s.lines = s.blankLines(s.height)
+		s.altActive = true
+	} else {
+		s.lines = s.mainLines
+		s.mainLines = nil
+		s.altActive = false
+		if saveCursor {
+			s.restoreCursor()
+		}
+	}
+	s.top, s.bottom = 0, s.height-1
+	s.wrapNext = false
+}
+
+func (s *Screen) sgr(params []int) {
+	if len(params) == 0 {
+		params = []int{0}
+	}
+
+	for i := 0; i < len(params); i++ {
+		p := params[i]
+		switch {
+		case p == 0:
+			s.style = defaultStyle
+		case p == 1:
+			s.style.attrs |= attrBold
+		case p == 2:
+			s.style.attrs |= attrDim
+		case p == 3:
+			s.style.attrs |= attrItalic
+		case p == 4:
+			s.style.attrs |= attrUnderline
+		case p == 5 || p == 6:
+			s.style.attrs |= attrBlink
+		case p == 7:
+			s.style.attrs |= attrReverse
+		case p == 8:
+			s.style.attrs |= attrHidden
+		case p == 9:
+			s.style.attrs |= attrStrike
+		case p == 21 || p == 22:
+			s.style.attrs &^= attrBold | attrDim
+		case p == 23:
+			s.style.attrs &^= attrItalic
+		case p == 24:
+			s.style.attrs &^= attrUnderline
+		case p == 25:
+			s.style.attrs &^= attrBlink
+		case p == 27:
+			s.style.attrs &^= attrReverse
+		case p == 28:
+			s.style.attrs &^= attrHidden
+		case p == 29:
+			s.style.attrs &^= attrStrike
+		case p >= 30 && p <= 37:
+			s.style.fg = int32(p - 30)
+		case p == 38 || p == 48:
+			color, consumed := extendedColor(params[i+1:])
+			i += consumed
+			if consumed == 0 {
+				continue
+			}
+			if p == 38 {
+				s.style.fg = color
+			} else {
+				s.style.bg = color
+			}
+		case p == 39:
+			s.style.fg = colorDefault
+		case p >= 40 && p <= 47:
+			s.style.bg = int32(p - 40)
+		case p == 49:
+			s.style.bg = colorDefault
+		case p >= 90 && p <= 97:
+			s.style.fg = int32(p - 90 + 8)
+		case p >= 100 && p <= 107:
+			s.style.bg = int32(p - 100 + 8)
+		}
+	}
+}
+
+func extendedColor(params []int) (int32, int) {
+	if len(params) == 0 {
+		return 0, 0
+	}
+	switch params[0] {
+	case 5:
+		if len(params) < 2 {
+			return 0, len(params)
+		}
+		return int32(params[1] & 0xff), 2
+	case 2:
+		if len(params) < 4 {
+			return 0, len(params)
+		}
+		r, g, b := params[1]&0xff, params[2]&0xff, params[3]&0xff
+		return int32(colorRGB | r<<16 | g<<8 | b), 4
+	}
+	return 0, 1
+}
+
+// Render draws the visible screen, or an older window into scrollback when
+// offset is positive. Every row is padded to the screen width so the result
+// can be laid out with lipgloss.
+func (s *Screen) Render(offset int, showCursor bool) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var rows [][]cell
+	offset = min(max(offset, 0), len(s.scrollback))
+	if offset > 0 && !s.altActive {
+		back := s.scrollback[len(s.scrollback)-offset:]
+		rows = append(rows, back...)
+		rows = append(rows, s.lines...)
+		rows = rows[:s.height]
+		showCursor = false
+	} else {
+		rows = s.lines
+	}
+
+	cursorVisible := showCursor && s.cursorVisible
+	var b strings.Builder
+	for y, line := range rows {
+		current := defaultStyle
+		for x, c := range line {
+			if c.cont {
+				continue
+			}
+			st := c.style
+			if cursorVisible && y == s.cy && x == s.cx {
+				st.attrs ^= attrReverse
+			}
+			if st != current {
+				b.WriteString(sgrSequence(st))
+				current = st
+			}
+			if c.r == 0 {
+				b.WriteByte(' ')
+			} else {
+				b.WriteRune(c.r)
+			}
+		}
+		if current != defaultStyle {
+			b.WriteString("\x1b[0m")
+		}
+		if y < len(rows)-1 {
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
+
+// Text returns the plain text of the visible screen with trailing spaces
+// removed from each row.
+func (s *Screen) Text() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rows := make([]string, len(s.lines))
+	for y, line := range s.lines {
+		var b strings.Builder
+		for _, c := range line {
+			if !c.cont {
+				b.WriteRune(c.r)
+			}
+		}
+		rows[y] = strings.TrimRight(b.String(), " ")
+	}
+	return strings.Join(rows, "\n")
+}
+
+func sgrSequence(st style) string {
+	codes := []string{"0"}
+	if st.attrs&attrBold != 0 {
+		codes = append(codes, "1")
+	}
+	if st.attrs&attrDim != 0 {
+		codes = append(codes, "2")
+	}
+	if st.attrs&attrItalic != 0 {
+		codes = append(codes, "3")
+	}
+	if st.attrs&attrUnderline != 0 {
+		codes = append(codes, "4")
+	}
+	if st.attrs&attrBlink != 0 {
+		codes = append(codes, "5")
+	}
+	if st.attrs&attrReverse != 0 {
+		codes = append(codes, "7")
+	}
+	if st.attrs&attrHidden != 0 {
+		codes = append(codes, "8")
+	}
+	if st.attrs&attrStrike != 0 {
+		codes = append(codes, "9")
+	}
+	codes = appendColor(codes, st.fg, 30)
+	codes = appendColor(codes, st.bg, 40)
+	return "\x1b[" + strings.Join(codes, ";") + "m"
+}
+
+func appendColor(codes []string, color int32, base int) []string {
+	switch {
+	case color == colorDefault:
+		return codes
+	case color&colorRGB != 0:
+		return append(codes, fmt.Sprintf("%d;2;%d;%d;%d", base+8, color>>16&0xff, color>>8&0xff, color&0xff))
+	case color < 8:
+		return append(codes, strconv.Itoa(base+int(color)))
+	case color < 16:
+		return append(codes, strconv.Itoa(base+60+int(color)-8))
+	default:
+		return append(codes, fmt.Sprintf("%d;5;%d", base+8, color))
+	}
+}
@@ -0,0 +1,36 @@
+package terminal
+
+import "testing"
+
+// Sequences with negative or huge parameters used to move the cursor off
+// the screen and panic on the next write.
+func TestCSIOutOfRangeParams(t *testing.T) {
+	sequences := []string{
+		"\x1b[-5A",
+		"\x1b[-5B",
+		"\x1b[-5C",
+		"\x1b[-5D",
+		"\x1b[-2S",
+		"\x1b[-2T",
+		"\x1b[-3@",
+		"\x1b[-3P",
+		"\x1b[-3X",
+		"\x1b[-3L",
+		"\x1b[-3M",
+		"\x1b[-1;-1H",
+		"\x1b[-4G",
+		"\x1b[-4d",
+		"\x1b[-1;-1r",
+		"\x1b[99999999999999999999B",
+		"\x1b[99999999999999999999C",
+		"\x1b[99999999999999999999@",
+	}
+
+	for _, seq := range sequences {
+		s := NewScreen(20, 5, nil)
+		s.Write([]byte("ab\r\n" + seq + "x"))
+		if s.cx < 0 || s.cx >= s.width || s.cy < 0 || s.cy >= s.height {
+			t.Errorf("%q left the cursor at %d,%d on a %dx%d screen", seq, s.cx, s.cy, s.width, s.height)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package terminal
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/creack/pty"
+)
+
+// OutputMsg reports that the program wrote to the terminal and the view
+// should be redrawn.
+type OutputMsg struct {
+	ID int64
+}
+
+// ExitedMsg reports that the program running in the terminal has exited.
+type ExitedMsg struct {
+	ID  int64
+	Err error
+}
+
+var lastID atomic.Int64
+
+// Model is a PTY-backed terminal that can be embedded in a Bubble Tea view.
+// Output from the program is parsed into a Screen, and key presses are
+// forwarded to the program as terminal input.
+type Model struct {
+	id      int64
+	cmd     *exec.Cmd
+	pty     *os.File
+	screen  *Screen
+	updates chan struct{}
+	err     error
+	exited  bool
+	offset  int
+
+	closeOnce sync.Once
+}
+
+// Start runs cmd attached to a new pseudo-terminal of the given size.
+func Start(cmd *exec.Cmd, width, height int) (*Model, error) {
+	width = max(width, 1)
+	height = max(height, 1)
+
+	m := &Model{
+		id:      lastID.Add(1),
+		cmd:     cmd,
+		updates: make(chan struct{}, 1),
+	}
+	m.screen = NewScreen(width, height, m.reply)
+
+	ptmx, err := pty.StartWithSize(cmd, &pty.Winsize{Rows: uint16(height), Cols: uint16(width)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start terminal: %w", err)
+	}
+	m.pty = ptmx
+
+	go m.readLoop()
+	return m, nil
+}
+
+func (m *Model) readLoop() {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := m.pty.Read(buf)
+		if n > 0 {
+			m.screen.Write(buf[:n])
+			select {
+			case m.updates <- struct{}{}:
+			default:
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	m.err = m.cmd.Wait()
+	close(m.updates)
+}
+
+func (m *Model) reply(b []byte) {
+	m.pty.Write(b)
+}
+
+func (m *Model) waitForOutput() tea.Cmd {
+	return func() tea.Msg {
+		if _, ok := <-m.updates; ok {
+			return OutputMsg{ID: m.id}
+		}
+		return ExitedMsg{ID: m.id, Err: m.err}
+	}
+}
+
+func (m *Model) ID() int64 {
+	return m.id
+}
+
+func (m *Model) Init() tea.Cmd {
+	return m.waitForOutput()
+}
+
+func (m *Model) Update(msg tea.Msg) (*Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case OutputMsg:
+		if msg.ID == m.id {
+			return m, m.waitForOutput()
+		}
+
+	case ExitedMsg:
+		if msg.ID == m.id {
+			m.exited = true
+		}
+
+	case tea.KeyMsg:
+		if m.exited {
+			return m, nil
+		}
+
+		_, height := m.screen.Size()
+		switch msg.String() {
+		case "shift+up":
+			m.scroll(1)
+			return m, nil
+		case "shift+down":
+			m.scroll(-1)
+			return m, nil
+		case "ctrl+pgup":
+			m.scroll(height / 2)
+			return m, nil
+		case "ctrl+pgdown":
+			m.scroll(-height / 2)
+			return m, nil
+		}
+
+		m.offset = 0
+		if b := keyBytes(msg, m.screen.AppCursorKeys()); len(b) > 0 {
+			m.pty.Write(b)
+		}
+	}
+
+	return m, nil
+}
+
+func (m *Model) scroll(lines int) {
+	m.offset = min(max(m.offset+lines, 0), m.screen.ScrollbackLen())
+}
+
+func (m *Model) View() string {
+	return m.screen.Render(m.offset, !m.exited)
+}
+
+// ScrollOffset reports how many lines the view is scrolled back from the
+// live screen.
+func (m *Model) ScrollOffset() int {
+	return m.offset
+}
+
+func (m *Model) Exited() bool {
+	return m.exited
+}
+
+func (m *Model) SetSize(width, height int) {
+	width = max(width, 1)
+	height = max(height, 1)
+
+	m.screen.Resize(width, height)
+	if !m.exited {
+		pty.Setsize(m.pty, &pty.Winsize{Rows: uint16(height), Cols: uint16(width)})
+	}
+}
+
+// Close hangs up the terminal, which ends the shell and any jobs it started.
+func (m *Model) Close() error {
+	var err error
+	m.closeOnce.Do(func() {
+		if m.cmd.Process != nil && !m.exited {
+			m.cmd.Process.Signal(syscall.SIGHUP)
+		}
+		err = m.pty.Close()
+	})
+	return err
+}
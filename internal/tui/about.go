@@ -129,7 +129,7 @@ That's why we built RootCamp.
 Every lesson in RootCamp follows a simple pattern:
 
 1. **Learn**: We explain what a command does, why it exists, and when to use it
-2. **Practice**: We spin up a real, isolated sandbox environment (` + "`/tmp/rootcamp-{uuid}/`" + `)
+2. **Practice**: We spin up a real, isolated sandbox environment (` + "`/tmp/rootcamp-{id}/`" + ` by default)
 3. **Explore**: You use the actual command in a safe space with real files and directories
 4. **Validate**: Find the hidden secret code to prove you understand the concept
 
@@ -137,7 +137,7 @@ No multiple choice. No simulated terminal. You're using the _real_ tools, just i
 
 ## Safety First
 
-Everything happens in temporary directories under ` + "`/tmp`" + `. When you exit a lesson, the sandbox is automatically cleaned up. No clutter, no residue, no accidentally breaking your system.
+Everything happens in temporary directories under ` + "`/tmp`" + `, or wherever you point the sandbox root. When you exit a lesson, the sandbox is automatically cleaned up. No clutter, no residue, no accidentally breaking your system.
 
 You can experiment, make mistakes, and learn without fear.
 
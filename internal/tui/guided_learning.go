@@ -3,16 +3,13 @@ package tui
 import (
 	"database/sql"
 	"fmt"
-	"math/rand"
-	"os"
-	"os/exec"
 	"runtime"
 	"strings"
-	"time"
 
 	"github.com/bobparsons/rootcamp/internal/db"
 	"github.com/bobparsons/rootcamp/internal/lab"
 	"github.com/bobparsons/rootcamp/internal/lessons"
+	"github.com/bobparsons/rootcamp/internal/terminal"
 	"github.com/bobparsons/rootcamp/internal/types"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
@@ -23,10 +20,18 @@ import (
 )
 
 const (
-	stateGuidedCourseOverview = iota
+	stateGuidedCatalog = iota
+	stateGuidedCourseOverview
 	stateGuidedLessonDetail
+	stateGuidedLabSession
 	stateGuidedCodeInput
 	stateGuidedSuccess
+	stateGuidedRestoreFile
+	stateGuidedLabChanges
+	stateGuidedPlacementIntro
+	stateGuidedPlacementResults
+	stateGuidedQuiz
+	stateGuidedQuizResults
 )
 
 type guidedShellFinishedMsg struct{}
@@ -37,8 +42,13 @@ type GuidedLearningModel struct {
 	width            int
 	height           int
 	state            int
+	catalogForm      *huh.Form
 	form             *huh.Form
+	selectedCourseID string
 	selectedLessonID string
+	courses          []types.Course
+	currentCourse    *types.Course
+	courseProgress   map[string]*types.CourseProgress
 	courseLessons    []types.CourseLessonItem
 	allLessons       []types.Lesson
 	renderedAbout    map[string]string
@@ -50,6 +60,16 @@ type GuidedLearningModel struct {
 	currentLesson    *types.Lesson
 	settings         *types.Settings
 	generatedSecret  string
+	labAttemptID     int64
+	terminal         *terminal.Model
+	labGuide         viewport.Model
+	labStep          int
+	stepCheckPending bool
+	restoreForm      *huh.Form
+	restorePath      string
+	labChanges       []lab.Change
+	placement        *placementTest
+	quiz             *quizSession
 }
 
 func NewGuidedLearningModel(database *sql.DB) GuidedLearningModel {
@@ -57,7 +77,6 @@ func NewGuidedLearningModel(database *sql.DB) GuidedLearningModel {
 	allLessons := []types.Lesson{}
 	renderedAbout := make(map[string]string)
 	progressMap := make(map[string]*types.UserProgress)
-	courseLessons := []types.CourseLessonItem{}
 
 	if err == nil {
 		allLessons = data.Lessons
@@ -82,10 +101,10 @@ func NewGuidedLearningModel(database *sql.DB) GuidedLearningModel {
 		if database != nil {
 			progressMap, _ = db.GetAllProgress(database)
 		}
-
-		courseLessons, _ = lessons.GetCourseLessons(progressMap, allLessons)
 	}
 
+	courses, _ := lessons.LoadCourses()
+
 	ti := textinput.New()
 	ti.Placeholder = "Enter your answer here..."
 	ti.CharLimit = 200
@@ -94,11 +113,11 @@ func NewGuidedLearningModel(database *sql.DB) GuidedLearningModel {
 	return GuidedLearningModel{
 		database:      database,
 		isOpen:        false,
-		state:         stateGuidedCourseOverview,
+		state:         stateGuidedCatalog,
 		allLessons:    allLessons,
 		renderedAbout: renderedAbout,
 		progressMap:   progressMap,
-		courseLessons: courseLessons,
+		courses:       courses,
 		codeInput:     ti,
 	}
 }
@@ -113,7 +132,54 @@ func (m *GuidedLearningModel) Update(msg tea.Msg) (*GuidedLearningModel, tea.Cmd
 	}
 
 	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		resizeLabSession(m.terminal, &m.labGuide, m.currentLesson, m.labStep, m.width, m.height)
+		return m, nil
+
+	case terminal.OutputMsg:
+		if m.terminal == nil {
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.terminal, cmd = m.terminal.Update(msg)
+		if m.watchesSteps() && !m.stepCheckPending {
+			m.stepCheckPending = true
+			cmd = tea.Batch(cmd, scheduleStepCheck())
+		}
+		return m, cmd
+
+	case stepCheckMsg:
+		m.stepCheckPending = false
+		if !m.watchesSteps() || m.state != stateGuidedLabSession {
+			return m, nil
+		}
+		lesson := lab.ApplySecret(*m.currentLesson, m.generatedSecret)
+		if done, _ := m.advanceSteps(lesson, ""); done {
+			return m, m.validateAnswer()
+		}
+		return m, nil
+
+	case terminal.ExitedMsg:
+		if m.terminal == nil || msg.ID != m.terminal.ID() {
+			return m, nil
+		}
+		m.closeTerminal()
+		return m, func() tea.Msg { return guidedShellFinishedMsg{} }
+
 	case guidedShellFinishedMsg:
+		m.labChanges = nil
+		if m.sandboxPath != "" {
+			m.labChanges, _ = lab.Diff(m.sandboxPath)
+		}
+		if m.currentLesson != nil && !lab.NeedsAnswer(lab.AtStep(*m.currentLesson, m.labStep)) {
+			m.validateAnswer()
+			if m.state != stateGuidedSuccess {
+				m.state = stateGuidedLabChanges
+			}
+			return m, nil
+		}
 		m.state = stateGuidedCodeInput
 		m.codeInput.Focus()
 		return m, nil
@@ -121,19 +187,18 @@ func (m *GuidedLearningModel) Update(msg tea.Msg) (*GuidedLearningModel, tea.Cmd
 	case tea.KeyMsg:
 		switch m.state {
 		case stateGuidedSuccess:
+			if (msg.String() == "enter" || msg.String() == " ") && m.placement != nil {
+				m.feedback = ""
+				m.continuePlacement()
+				return m, nil
+			}
 			if msg.String() == "enter" || msg.String() == " " {
 				m.state = stateGuidedCourseOverview
 				m.selectedLessonID = ""
 				m.currentLesson = nil
 				m.feedback = ""
 
-				if m.database != nil {
-					progressMap, _ := db.GetAllProgress(m.database)
-					m.progressMap = progressMap
-				}
-				courseLessons, _ := lessons.GetCourseLessons(m.progressMap, m.allLessons)
-				m.courseLessons = courseLessons
-
+				m.refreshProgress()
 				m.createForm()
 				if m.form != nil {
 					return m, m.form.Init()
@@ -143,10 +208,15 @@ func (m *GuidedLearningModel) Update(msg tea.Msg) (*GuidedLearningModel, tea.Cmd
 
 		case stateGuidedCodeInput:
 			switch msg.String() {
-			case "esc", "q":
-				m.state = stateGuidedLessonDetail
+			case "esc":
 				m.codeInput.SetValue("")
 				m.feedback = ""
+				if m.terminal != nil {
+					m.codeInput.Blur()
+					m.state = stateGuidedLabSession
+					return m, nil
+				}
+				m.state = stateGuidedLessonDetail
 				return m, nil
 			case "enter":
 				return m, m.validateAnswer()
@@ -156,39 +226,205 @@ func (m *GuidedLearningModel) Update(msg tea.Msg) (*GuidedLearningModel, tea.Cmd
 				return m, cmd
 			}
 
+		case stateGuidedLabSession:
+			switch msg.String() {
+			case labCheckKey:
+				if m.currentLesson != nil && !lab.NeedsAnswer(lab.AtStep(*m.currentLesson, m.labStep)) {
+					return m, m.validateAnswer()
+				}
+				m.state = stateGuidedCodeInput
+				m.feedback = ""
+				m.codeInput.Focus()
+				return m, nil
+			case labLeaveKey:
+				m.closeLab()
+				m.state = stateGuidedLessonDetail
+				return m, nil
+			case "ctrl+up":
+				m.labGuide.LineUp(1)
+				return m, nil
+			case "ctrl+down":
+				m.labGuide.LineDown(1)
+				return m, nil
+			}
+			if m.terminal == nil {
+				return m, nil
+			}
+			var cmd tea.Cmd
+			m.terminal, cmd = m.terminal.Update(msg)
+			return m, cmd
+
+		case stateGuidedLabChanges:
+			switch msg.String() {
+			case "enter", "esc":
+				m.state = stateGuidedLessonDetail
+				return m, nil
+			case "s":
+				return m, m.resumeLab()
+			case "r":
+				return m, m.resetLab()
+			case "f":
+				return m, m.openRestore()
+			}
+			return m, nil
+
+		case stateGuidedRestoreFile:
+			if msg.String() == "esc" {
+				m.restoreForm = nil
+				m.state = stateGuidedLessonDetail
+				return m, nil
+			}
+
 		case stateGuidedLessonDetail:
 			switch msg.String() {
 			case "esc", "q":
+				if m.placement != nil {
+					m.finishPlacement()
+					return m, nil
+				}
+				m.closeLab()
 				m.state = stateGuidedCourseOverview
 				m.selectedLessonID = ""
 				m.currentLesson = nil
 				m.createForm()
 				return m, m.form.Init()
 			case "s":
+				if m.currentLesson != nil && m.currentLesson.Quiz != nil {
+					return m, m.startQuiz()
+				}
 				if m.currentLesson != nil && m.currentLesson.SkipSandbox {
 					m.state = stateGuidedCodeInput
 					m.codeInput.Focus()
 					return m, nil
 				}
+				if m.sandboxPath != "" {
+					return m, m.resumeLab()
+				}
 				return m, m.startLab()
+			case "r":
+				if m.sandboxPath != "" {
+					return m, m.resetLab()
+				}
+			case "f":
+				if m.sandboxPath != "" {
+					return m, m.openRestore()
+				}
+			case "d":
+				if m.sandboxPath != "" {
+					m.labChanges, _ = lab.Diff(m.sandboxPath)
+					m.state = stateGuidedLabChanges
+					return m, nil
+				}
 			case "c":
+				if m.currentLesson != nil && m.currentLesson.Quiz != nil {
+					return m, nil
+				}
 				m.state = stateGuidedCodeInput
 				m.codeInput.Focus()
 				return m, nil
+			case "k":
+				if m.placement != nil {
+					m.skipPlacementModule()
+					return m, nil
+				}
 			default:
 				var cmd tea.Cmd
 				m.viewport, cmd = m.viewport.Update(msg)
 				return m, cmd
 			}
 
+		case stateGuidedQuiz:
+			if msg.String() == "esc" {
+				m.quiz = nil
+				m.state = stateGuidedLessonDetail
+				return m, nil
+			}
+
+		case stateGuidedQuizResults:
+			switch msg.String() {
+			case "enter", " ":
+				if m.quiz.passed {
+					m.quiz = nil
+					m.state = stateGuidedSuccess
+					return m, nil
+				}
+			case "r":
+				if !m.quiz.passed {
+					return m, m.startQuiz()
+				}
+			case "esc":
+				if !m.quiz.passed {
+					m.quiz = nil
+					m.state = stateGuidedLessonDetail
+				}
+			}
+			return m, nil
+
 		case stateGuidedCourseOverview:
 			if msg.String() == "esc" || msg.String() == "q" {
-				m.isOpen = false
-				m.state = stateGuidedCourseOverview
+				m.state = stateGuidedCatalog
 				m.selectedLessonID = ""
+				m.refreshProgress()
+				m.createCatalogForm()
+				if m.catalogForm != nil {
+					return m, m.catalogForm.Init()
+				}
 				return m, nil
 			}
+
+		case stateGuidedCatalog:
+			switch msg.String() {
+			case "esc", "q":
+				m.isOpen = false
+				m.selectedCourseID = ""
+				return m, nil
+			case "t":
+				m.startPlacement()
+				return m, nil
+			}
+
+		case stateGuidedPlacementIntro:
+			switch msg.String() {
+			case "enter":
+				m.nextPlacementTier()
+				return m, nil
+			case "esc", "q":
+				return m, m.endPlacement()
+			}
+			return m, nil
+
+		case stateGuidedPlacementResults:
+			switch msg.String() {
+			case "enter", "esc", "q":
+				return m, m.endPlacement()
+			}
+			return m, nil
+		}
+	}
+
+	if m.state == stateGuidedRestoreFile && m.restoreForm != nil {
+		return m, m.updateRestoreForm(msg)
+	}
+
+	if m.state == stateGuidedQuiz && m.quiz != nil {
+		done, cmd := m.quiz.update(msg)
+		if done {
+			m.finishQuiz()
+		}
+		return m, cmd
+	}
+
+	if m.state == stateGuidedCatalog && m.catalogForm != nil {
+		form, cmd := m.catalogForm.Update(msg)
+		if f, ok := form.(*huh.Form); ok {
+			m.catalogForm = f
+		}
+
+		if m.catalogForm.State == huh.StateCompleted {
+			return m, m.openCourse(m.selectedCourseID)
 		}
+
+		return m, cmd
 	}
 
 	if m.state == stateGuidedCourseOverview && m.form != nil {
@@ -232,40 +468,31 @@ func (m *GuidedLearningModel) validateAnswer() tea.Cmd {
 	userInput := strings.TrimSpace(m.codeInput.Value())
 
 	lesson := *m.currentLesson
-	if lesson.SkipSandbox && m.generatedSecret != "" {
-		for i := range lesson.Requirements {
-			lesson.Requirements[i].Expected = strings.ReplaceAll(
-				lesson.Requirements[i].Expected,
-				"{SECRET_CODE}",
-				m.generatedSecret,
-			)
-		}
+	if m.generatedSecret != "" {
+		lesson = lab.ApplySecret(lesson, m.generatedSecret)
 	}
 
-	valid, errorMsg := lab.ValidateLesson(lesson, userInput, m.sandboxPath)
-
-	if valid {
-		db.MarkComplete(m.database, m.currentLesson.ID)
-
-		if m.sandboxPath != "" {
-			lab.Cleanup(m.sandboxPath)
-			m.sandboxPath = ""
-		}
-
-		progress, _ := db.GetProgress(m.database, m.currentLesson.ID)
-		if progress != nil {
-			m.progressMap[m.currentLesson.ID] = progress
+	var valid bool
+	var errorMsg string
+	if len(lesson.Steps) > 0 {
+		before := m.labStep
+		valid, errorMsg = m.advanceSteps(lesson, userInput)
+		if !valid && m.labStep > before {
+			m.codeInput.SetValue("")
+			if m.terminal != nil {
+				m.codeInput.Blur()
+				m.state = stateGuidedLabSession
+			}
+			return nil
 		}
-
-		m.state = stateGuidedSuccess
-		m.feedback = "Congratulations! You've completed this lesson! 🎉"
 	} else {
-		db.IncrementAttempts(m.database, m.currentLesson.ID)
+		valid, errorMsg = lab.ValidateLesson(lesson, userInput, m.sandboxPath)
+	}
 
-		progress, _ := db.GetProgress(m.database, m.currentLesson.ID)
-		if progress != nil {
-			m.progressMap[m.currentLesson.ID] = progress
-		}
+	if valid {
+		m.lessonPassed()
+	} else {
+		m.recordFailedAttempt()
 
 		if errorMsg != "" {
 			m.feedback = fmt.Sprintf("❌ Incorrect. Hint: %s", errorMsg)
@@ -278,73 +505,227 @@ func (m *GuidedLearningModel) validateAnswer() tea.Cmd {
 	return nil
 }
 
+// lessonPassed completes the current lesson, or counts it towards the tier
+// during a placement test.
+func (m *GuidedLearningModel) lessonPassed() {
+	if m.placement != nil {
+		m.recordLabAttempt(true)
+		m.closeLab()
+		m.passPlacementChallenge()
+		return
+	}
+
+	db.MarkComplete(m.database, m.currentLesson.ID)
+
+	m.recordLabAttempt(true)
+	m.closeLab()
+
+	progress, _ := db.GetProgress(m.database, m.currentLesson.ID)
+	if progress != nil {
+		m.progressMap[m.currentLesson.ID] = progress
+	}
+	m.recordCompletedCourses()
+
+	m.state = stateGuidedSuccess
+	m.feedback = "Congratulations! You've completed this lesson! 🎉"
+}
+
+func (m *GuidedLearningModel) recordFailedAttempt() {
+	db.IncrementAttempts(m.database, m.currentLesson.ID)
+
+	progress, _ := db.GetProgress(m.database, m.currentLesson.ID)
+	if progress != nil {
+		m.progressMap[m.currentLesson.ID] = progress
+	}
+}
+
+func (m *GuidedLearningModel) startQuiz() tea.Cmd {
+	quiz := prepareQuiz(*m.currentLesson.Quiz, func(text string) string {
+		return replaceGuidedPlaceholders(text, m.generatedSecret)
+	})
+	m.quiz = newQuizSession(quiz)
+	m.feedback = ""
+	m.state = stateGuidedQuiz
+	return m.quiz.form.Init()
+}
+
+// finishQuiz grades the quiz and saves the answers. A passed quiz completes
+// the lesson, though the results are shown before the success screen.
+func (m *GuidedLearningModel) finishQuiz() {
+	m.quiz.grade()
+	db.RecordQuizAttempt(m.database, m.currentLesson.ID, m.quiz.results, m.quiz.passed)
+
+	if m.quiz.passed {
+		m.lessonPassed()
+	} else {
+		m.recordFailedAttempt()
+	}
+	m.state = stateGuidedQuizResults
+}
+
+// watchesSteps reports whether the lab is on a step of a multi-step lesson
+// that is graded on the sandbox alone, so it can be ticked off as the
+// learner works rather than waiting for F2.
+func (m *GuidedLearningModel) watchesSteps() bool {
+	if m.currentLesson == nil || m.terminal == nil || len(m.currentLesson.Steps) == 0 {
+		return false
+	}
+	return !lab.NeedsAnswer(lab.AtStep(*m.currentLesson, m.labStep))
+}
+
+// advanceSteps checks a multi-step lesson from the learner's current step,
+// saving and announcing any steps that are now complete. It reports whether
+// every step is done.
+func (m *GuidedLearningModel) advanceSteps(lesson types.Lesson, userInput string) (bool, string) {
+	done, errorMsg := lab.CheckSteps(lesson, m.labStep, userInput, m.sandboxPath)
+	if done > m.labStep {
+		saveStepProgress(m.database, lesson, m.labStep, done)
+		m.labStep = done
+		m.labGuide.SetContent(renderLabGuide(*m.currentLesson, done, m.labGuide.Width))
+		m.labGuide.GotoTop()
+		m.feedback = stepFeedback(lesson, done)
+	}
+	return done == len(lesson.Steps), errorMsg
+}
+
 func (m *GuidedLearningModel) startLab() tea.Cmd {
 	if m.currentLesson == nil {
 		return nil
 	}
 
-	sandboxPath, err := lab.Create(*m.currentLesson)
+	m.closeLab()
+
+	secret := lab.NewSecret()
+	lesson := lab.ApplySecret(*m.currentLesson, secret)
+	sandboxPath, err := lab.Create(lesson)
 	if err != nil {
 		m.feedback = fmt.Sprintf("Failed to create sandbox: %v", err)
 		return nil
 	}
 
+	layout := computeLabLayout(m.width, m.height)
+	term, err := startLabTerminal(lesson, sandboxPath, m.settings, layout)
+	if err != nil {
+		lab.Cleanup(sandboxPath)
+		m.feedback = fmt.Sprintf("Failed to start terminal: %v", err)
+		return nil
+	}
+
 	m.sandboxPath = sandboxPath
-	startPath := lab.GetStartPath(sandboxPath, *m.currentLesson)
-
-	useBasicBash := false
-	if m.settings != nil {
-		useBasicBash = m.settings.UseBasicBash
-	}
-
-	var instructions string
-	if useBasicBash {
-		instructions = fmt.Sprintf(`clear
-printf '\033[1;96m╔══════════════════════════════════════════════════════════════════════════════╗\n'
-printf '║\033[0m\033[1;92m                          ROOT CAMP - LAB SESSION                             \033[1;96m║\n'
-printf '╚══════════════════════════════════════════════════════════════════════════════╝\033[0m\n\n'
-printf '\033[1;33mLesson:\033[0m \033[1;97m%s\033[0m\n\n'
-cat << 'EOF'
-%s
-EOF
-printf '\n\033[1;36mYour sandbox is located at:\033[0m\n'
-printf '  \033[36m%s\033[0m\n\n'
-printf '\033[35mWhen you'"'"'re done, type \033[1;91mexit\033[0m\033[35m to return to Root Camp and enter your answer.\033[0m\n\n'
-printf '\033[1;32mGood luck!\033[0m\n\n'
-cd '%s'
-exec bash`, m.currentLesson.Title, m.currentLesson.Instructions, startPath, startPath)
+	m.generatedSecret = secret
+	m.terminal = term
+	m.labChanges = nil
+	m.labAttemptID, _ = db.StartLabAttempt(m.database, m.currentLesson.ID, secret)
+	m.labStep = 0
+	m.labGuide = newLabGuide(*m.currentLesson, m.labStep, layout)
+	m.feedback = ""
+	m.state = stateGuidedLabSession
+
+	return m.terminal.Init()
+}
+
+// resumeLab opens a new shell in the sandbox left behind by the previous
+// one, keeping the learner's work and the current attempt.
+func (m *GuidedLearningModel) resumeLab() tea.Cmd {
+	if m.currentLesson == nil || m.sandboxPath == "" {
+		return nil
+	}
+
+	layout := computeLabLayout(m.width, m.height)
+	term, err := startLabTerminal(lab.ApplySecret(*m.currentLesson, m.generatedSecret), m.sandboxPath, m.settings, layout)
+	if err != nil {
+		m.feedback = fmt.Sprintf("Failed to start terminal: %v", err)
+		return nil
+	}
+
+	m.terminal = term
+	m.labGuide = newLabGuide(*m.currentLesson, m.labStep, layout)
+	m.feedback = ""
+	m.state = stateGuidedLabSession
+
+	return m.terminal.Init()
+}
+
+func (m *GuidedLearningModel) resetLab() tea.Cmd {
+	if m.currentLesson == nil || m.sandboxPath == "" {
+		return nil
+	}
+
+	m.closeTerminal()
+	if err := lab.Reset(m.sandboxPath, lab.ApplySecret(*m.currentLesson, m.generatedSecret)); err != nil {
+		m.feedback = fmt.Sprintf("Failed to reset lab: %v", err)
+		return nil
+	}
+	if m.labAttemptID != 0 {
+		db.RecordLabReset(m.database, m.labAttemptID)
+	}
+	m.labStep = 0
+
+	return m.resumeLab()
+}
+
+func (m *GuidedLearningModel) openRestore() tea.Cmd {
+	changed, err := lab.ChangedFiles(m.sandboxPath)
+	if err != nil {
+		m.feedback = fmt.Sprintf("Failed to check sandbox: %v", err)
+		return nil
+	}
+	if len(changed) == 0 {
+		m.feedback = "Nothing to restore: every original file is unchanged"
+		return nil
+	}
+
+	m.restorePath = ""
+	m.restoreForm = newRestoreForm(changed, &m.restorePath)
+	m.state = stateGuidedRestoreFile
+	return m.restoreForm.Init()
+}
+
+func (m *GuidedLearningModel) updateRestoreForm(msg tea.Msg) tea.Cmd {
+	form, cmd := m.restoreForm.Update(msg)
+	if f, ok := form.(*huh.Form); ok {
+		m.restoreForm = f
+	}
+
+	if m.restoreForm.State != huh.StateCompleted {
+		return cmd
+	}
+
+	if err := lab.RestoreFile(m.sandboxPath, m.restorePath); err != nil {
+		m.feedback = fmt.Sprintf("Failed to restore %s: %v", m.restorePath, err)
 	} else {
-		userShell := os.Getenv("SHELL")
-		if userShell == "" {
-			userShell = "/bin/bash"
-		}
-
-		instructions = fmt.Sprintf(`clear
-printf '\033[1;96m╔══════════════════════════════════════════════════════════════════════════════╗\n'
-printf '║\033[0m\033[1;92m                          ROOT CAMP - LAB SESSION                             \033[1;96m║\n'
-printf '╚══════════════════════════════════════════════════════════════════════════════╝\033[0m\n\n'
-printf '\033[1;33mLesson:\033[0m \033[1;97m%s\033[0m\n\n'
-cat << 'EOF'
-%s
-EOF
-printf '\n\033[1;36mYour sandbox is located at:\033[0m\n'
-printf '  \033[36m%s\033[0m\n\n'
-printf '\033[35mWhen you'"'"'re done, type \033[1;91mexit\033[0m\033[35m to return to Root Camp and enter your answer.\033[0m\n\n'
-printf '\033[1;32mGood luck!\033[0m\n\n'
-cd '%s'
-exec %s`, m.currentLesson.Title, m.currentLesson.Instructions, startPath, startPath, userShell)
-	}
-
-	c := exec.Command("bash", "-c", instructions)
-	c.Dir = startPath
-	c.Stdin = os.Stdin
-	c.Stdout = os.Stdout
-	c.Stderr = os.Stderr
-	c.Env = append(os.Environ(), fmt.Sprintf("PS1=rootcamp:%s$ ", m.currentLesson.Code))
-
-	return tea.ExecProcess(c, func(err error) tea.Msg {
-		return guidedShellFinishedMsg{}
-	})
+		m.feedback = fmt.Sprintf("Restored %s. Press S to resume the lab.", m.restorePath)
+	}
+	m.restoreForm = nil
+	m.state = stateGuidedLessonDetail
+	return nil
+}
+
+func (m *GuidedLearningModel) closeTerminal() {
+	if m.terminal != nil {
+		m.terminal.Close()
+		m.terminal = nil
+	}
+}
+
+func (m *GuidedLearningModel) recordLabAttempt(passed bool) {
+	if m.labAttemptID == 0 {
+		return
+	}
+	history, _ := lab.ReadHistory(m.sandboxPath)
+	db.FinishLabAttempt(m.database, m.labAttemptID, passed, history)
+	m.labAttemptID = 0
+}
+
+func (m *GuidedLearningModel) closeLab() {
+	m.closeTerminal()
+	m.recordLabAttempt(false)
+	if m.sandboxPath != "" {
+		lab.Cleanup(m.sandboxPath)
+		m.sandboxPath = ""
+		m.generatedSecret = ""
+		m.labStep = 0
+	}
 }
 
 func (m GuidedLearningModel) View() string {
@@ -353,8 +734,24 @@ func (m GuidedLearningModel) View() string {
 	}
 
 	switch m.state {
+	case stateGuidedCatalog:
+		return m.renderCatalogView()
+	case stateGuidedPlacementIntro:
+		return m.renderPlacementIntroView()
+	case stateGuidedPlacementResults:
+		return m.renderPlacementResultsView()
 	case stateGuidedSuccess:
 		return m.renderSuccessView()
+	case stateGuidedQuiz:
+		return renderQuizView(m.currentLesson, m.quiz, m.width, m.height)
+	case stateGuidedQuizResults:
+		return renderQuizResultsView(m.quiz, m.width, m.height)
+	case stateGuidedRestoreFile:
+		return renderRestoreView(m.restoreForm, m.width, m.height)
+	case stateGuidedLabChanges:
+		return renderLabChangesView(m.labChanges, m.feedback, m.width, m.height)
+	case stateGuidedLabSession:
+		return renderLabSessionView(m.currentLesson, m.labStep, m.labGuide, m.terminal, m.feedback, m.width, m.height)
 	case stateGuidedCodeInput:
 		return m.renderCodeInputView()
 	case stateGuidedLessonDetail:
@@ -364,13 +761,13 @@ func (m GuidedLearningModel) View() string {
 	}
 }
 
-func (m *GuidedLearningModel) renderOverviewView() string {
-	if m.form == nil {
+func (m *GuidedLearningModel) renderCatalogView() string {
+	if m.catalogForm == nil {
 		errorMsg := lipgloss.NewStyle().
 			Foreground(lipgloss.Color("196")).
 			Bold(true).
 			Align(lipgloss.Center).
-			Render("No course lessons available.\n\nPlease check that lessons are properly configured.")
+			Render("No courses available.\n\nPlease check that courses are properly configured.")
 
 		return lipgloss.Place(
 			m.width,
@@ -381,13 +778,76 @@ func (m *GuidedLearningModel) renderOverviewView() string {
 		)
 	}
 
-	courseData, err := lessons.LoadCourse()
-	if err != nil {
+	finished := 0
+	for _, course := range m.courses {
+		completed, testedOut, total := m.courseStats(course)
+		if total > 0 && completed+testedOut == total {
+			finished++
+		}
+	}
+
+	title := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(AccentBlue).
+		Padding(1, 0).
+		Align(lipgloss.Center).
+		Render("🎯 Guided Learning")
+
+	description := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("241")).
+		Align(lipgloss.Center).
+		Render("Pick a course. Lessons shared between courses only need to be completed once.")
+
+	progressInfo := lipgloss.NewStyle().
+		Foreground(ColorGreen).
+		Bold(true).
+		Align(lipgloss.Center).
+		Render(fmt.Sprintf("Progress: %d/%d courses completed", finished, len(m.courses)))
+
+	formView := m.catalogForm.View()
+
+	instructions := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("241")).
+		Align(lipgloss.Center).
+		Render("↑/↓: Navigate | Enter: Open Course | T: Placement Test | ESC/Q: Return to Menu")
+
+	legend := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("241")).
+		Align(lipgloss.Center).
+		Render("[✓] Completed  [→] Last opened  [•] In progress")
+
+	content := lipgloss.JoinVertical(
+		lipgloss.Center,
+		"",
+		title,
+		"",
+		description,
+		"",
+		progressInfo,
+		"",
+		formView,
+		"",
+		legend,
+		"",
+		instructions,
+	)
+
+	return lipgloss.Place(
+		m.width,
+		m.height,
+		lipgloss.Center,
+		lipgloss.Center,
+		content,
+	)
+}
+
+func (m *GuidedLearningModel) renderOverviewView() string {
+	if m.form == nil || m.currentCourse == nil {
 		errorMsg := lipgloss.NewStyle().
 			Foreground(lipgloss.Color("196")).
 			Bold(true).
 			Align(lipgloss.Center).
-			Render(fmt.Sprintf("Failed to load course: %v", err))
+			Render("No course lessons available.\n\nPlease check that lessons are properly configured.")
 
 		return lipgloss.Place(
 			m.width,
@@ -399,6 +859,7 @@ func (m *GuidedLearningModel) renderOverviewView() string {
 	}
 
 	completed, total := lessons.GetCourseProgress(m.courseLessons)
+	testedOut := lessons.GetCourseTestedOut(m.courseLessons)
 	progressPercent := 0
 	if total > 0 {
 		progressPercent = (completed * 100) / total
@@ -415,18 +876,18 @@ func (m *GuidedLearningModel) renderOverviewView() string {
 		Foreground(AccentBlue).
 		Padding(1, 0).
 		Align(lipgloss.Center).
-		Render(fmt.Sprintf("🎯 Guided Learning - %s", courseData.Course.Title))
+		Render(fmt.Sprintf("🎯 Guided Learning - %s", m.currentCourse.Title))
 
 	description := lipgloss.NewStyle().
 		Foreground(lipgloss.Color("241")).
 		Align(lipgloss.Center).
-		Render(courseData.Course.Description)
+		Render(m.currentCourse.Description)
 
 	progressInfo := lipgloss.NewStyle().
 		Foreground(ColorGreen).
 		Bold(true).
 		Align(lipgloss.Center).
-		Render(fmt.Sprintf("Progress: %d/%d lessons completed", completed, total))
+		Render(overviewProgress(completed, testedOut, total))
 
 	progressBarView := lipgloss.NewStyle().
 		Foreground(ColorGreen).
@@ -438,12 +899,12 @@ func (m *GuidedLearningModel) renderOverviewView() string {
 	instructions := lipgloss.NewStyle().
 		Foreground(lipgloss.Color("241")).
 		Align(lipgloss.Center).
-		Render("↑/↓: Navigate | Enter: Start Lesson | ESC/Q: Return to Menu")
+		Render("↑/↓: Navigate | Enter: Start Lesson | ESC/Q: Back to Courses")
 
 	legend := lipgloss.NewStyle().
 		Foreground(lipgloss.Color("241")).
 		Align(lipgloss.Center).
-		Render("[✓] Completed  [→] Next  [L] Locked")
+		Render("[✓] Completed  [T] Tested out  [→] Available  [L] Locked")
 
 	content := lipgloss.JoinVertical(
 		lipgloss.Center,
@@ -471,6 +932,13 @@ func (m *GuidedLearningModel) renderOverviewView() string {
 	)
 }
 
+func overviewProgress(completed, testedOut, total int) string {
+	if testedOut > 0 {
+		return fmt.Sprintf("Progress: %d/%d lessons completed, %d tested out", completed, total, testedOut)
+	}
+	return fmt.Sprintf("Progress: %d/%d lessons completed", completed, total)
+}
+
 func (m *GuidedLearningModel) renderDetailView() string {
 	if m.currentLesson == nil {
 		return ""
@@ -483,12 +951,17 @@ func (m *GuidedLearningModel) renderDetailView() string {
 		Foreground(AccentBlue).
 		Padding(1, 0).
 		Width(contentWidth).
-		Render(fmt.Sprintf("📖 Lesson: %s", m.currentLesson.Title))
+		Render(m.detailTitle())
+
+	help := lessonDetailHelp(*m.currentLesson, m.sandboxPath != "")
+	if m.placement != nil {
+		help = strings.TrimSuffix(help, "ESC/Q to return") + "[K] Skip Module | ESC/Q to end test"
+	}
 
 	instructions := lipgloss.NewStyle().
 		Foreground(lipgloss.Color("241")).
 		Width(contentWidth).
-		Render("Arrow keys to scroll | [S] Start Lab | [C] Enter Code | ESC/Q to return")
+		Render(help)
 
 	feedbackStyle := lipgloss.NewStyle().
 		Foreground(lipgloss.Color("196")).
@@ -521,6 +994,13 @@ func (m *GuidedLearningModel) renderDetailView() string {
 	)
 }
 
+func (m *GuidedLearningModel) detailTitle() string {
+	if m.placement != nil {
+		return fmt.Sprintf("%s\n📖 Lesson: %s", m.placementTitle(), m.currentLesson.Title)
+	}
+	return fmt.Sprintf("📖 Lesson: %s", m.currentLesson.Title)
+}
+
 func (m *GuidedLearningModel) renderCodeInputView() string {
 	if m.currentLesson == nil {
 		return ""
@@ -538,7 +1018,7 @@ func (m *GuidedLearningModel) renderCodeInputView() string {
 		Foreground(lipgloss.Color("241")).
 		Width(m.width).
 		Align(lipgloss.Center).
-		Render("Type your answer and press Enter to submit | ESC/Q to cancel")
+		Render("Type your answer and press Enter to submit | ESC to cancel")
 
 	feedbackStyle := lipgloss.NewStyle().
 		Foreground(lipgloss.Color("196")).
@@ -577,13 +1057,20 @@ func (m *GuidedLearningModel) renderCodeInputView() string {
 }
 
 func (m *GuidedLearningModel) renderSuccessView() string {
+	heading := "🎉 Lesson Complete!"
+	next := "Press Enter or Space to return to course overview"
+	if m.placement != nil {
+		heading = "🧪 Challenge Passed!"
+		next = "Press Enter or Space to continue the placement test"
+	}
+
 	title := lipgloss.NewStyle().
 		Bold(true).
 		Foreground(ColorGreen).
 		Padding(1, 0).
 		Width(m.width).
 		Align(lipgloss.Center).
-		Render("🎉 Lesson Complete!")
+		Render(heading)
 
 	message := lipgloss.NewStyle().
 		Foreground(ColorGreen).
@@ -596,7 +1083,15 @@ func (m *GuidedLearningModel) renderSuccessView() string {
 		Foreground(lipgloss.Color("241")).
 		Width(m.width).
 		Align(lipgloss.Center).
-		Render("Press Enter or Space to return to course overview")
+		Render(next)
+
+	var changesView string
+	if len(m.labChanges) > 0 {
+		changesView = lipgloss.NewStyle().
+			Width(m.width).
+			Align(lipgloss.Center).
+			Render(renderChangeTree(m.labChanges))
+	}
 
 	content := lipgloss.JoinVertical(
 		lipgloss.Left,
@@ -607,6 +1102,8 @@ func (m *GuidedLearningModel) renderSuccessView() string {
 		"",
 		message,
 		"",
+		changesView,
+		"",
 		instructions,
 	)
 
@@ -631,11 +1128,15 @@ func (m *GuidedLearningModel) setupDetailView() {
 		rendered = "Lesson content not found"
 	}
 
-	if m.currentLesson != nil && m.currentLesson.SkipSandbox {
-		m.generatedSecret = generateGuidedSecretCode()
+	if m.currentLesson != nil && !lab.UsesSandbox(*m.currentLesson) {
+		m.generatedSecret = lab.NewSecret()
 		rendered = replaceGuidedPlaceholders(rendered, m.generatedSecret)
 	}
 
+	if m.currentLesson != nil && len(m.currentLesson.Steps) > 0 {
+		rendered += "\n\n" + stepProgressSummary(m.database, *m.currentLesson)
+	}
+
 	m.viewport.SetContent(rendered)
 	m.feedback = ""
 }
@@ -644,34 +1145,31 @@ func (m *GuidedLearningModel) createForm() {
 	m.selectedLessonID = ""
 
 	if len(m.courseLessons) == 0 {
+		m.form = nil
 		return
 	}
 
-	options := make([]huh.Option[string], len(m.courseLessons))
-	nextFound := false
+	options := make([]huh.Option[string], 0, len(m.courseLessons))
+	for _, node := range courseTree(m.courseLessons) {
+		item := m.courseLessons[node.index]
 
-	for i, item := range m.courseLessons {
 		var statusMark string
-		var label string
-
 		switch item.Status {
 		case types.LessonComplete:
 			statusMark = "✓"
-			label = fmt.Sprintf("[%s] %d. %s - %s", statusMark, item.Sequence, item.Lesson.Code, item.Lesson.Title)
+		case types.LessonTestedOut:
+			statusMark = "T"
 		case types.LessonUnlocked:
-			if !nextFound {
-				statusMark = "→"
-				nextFound = true
-			} else {
-				statusMark = " "
-			}
-			label = fmt.Sprintf("[%s] %d. %s - %s", statusMark, item.Sequence, item.Lesson.Code, item.Lesson.Title)
+			statusMark = "→"
 		case types.LessonLocked:
 			statusMark = "L"
-			label = fmt.Sprintf("[%s] %d. %s - %s", statusMark, item.Sequence, item.Lesson.Code, item.Lesson.Title)
 		}
 
-		options[i] = huh.NewOption(label, item.Lesson.ID)
+		label := fmt.Sprintf("%s[%s] %s - %s", node.prefix, statusMark, item.Lesson.Code, item.Lesson.Title)
+		if len(node.alsoNeeds) > 0 {
+			label += fmt.Sprintf(" (also needs %s)", strings.Join(node.alsoNeeds, ", "))
+		}
+		options = append(options, huh.NewOption(label, item.Lesson.ID))
 	}
 
 	m.form = huh.NewForm(
@@ -685,16 +1183,194 @@ func (m *GuidedLearningModel) createForm() {
 	).WithWidth(90)
 }
 
-func generateGuidedSecretCode() string {
-	const charset = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
-	const length = 12
-	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
-	b := make([]byte, length)
-	for i := range b {
-		b[i] = charset[rng.Intn(len(charset))]
+// openCourse shows the course's lesson list and records it as the course
+// the learner was last working on.
+func (m *GuidedLearningModel) openCourse(courseID string) tea.Cmd {
+	m.currentCourse = nil
+	for i := range m.courses {
+		if m.courses[i].ID == courseID {
+			m.currentCourse = &m.courses[i]
+			break
+		}
+	}
+	if m.currentCourse == nil {
+		m.createCatalogForm()
+		if m.catalogForm != nil {
+			return m.catalogForm.Init()
+		}
+		return nil
+	}
+
+	if m.database != nil {
+		db.OpenCourse(m.database, courseID)
+	}
+
+	m.state = stateGuidedCourseOverview
+	m.refreshProgress()
+	m.createForm()
+	if m.form != nil {
+		return m.form.Init()
+	}
+	return nil
+}
+
+// refreshProgress reloads lesson and course progress and the status of the
+// current course's lessons.
+func (m *GuidedLearningModel) refreshProgress() {
+	if m.database != nil {
+		if progressMap, err := db.GetAllProgress(m.database); err == nil {
+			m.progressMap = progressMap
+		}
+		if courseProgress, err := db.GetAllCourseProgress(m.database); err == nil {
+			m.courseProgress = courseProgress
+		}
+	}
+
+	m.courseLessons = nil
+	if m.currentCourse != nil {
+		m.courseLessons = lessons.GetCourseLessons(*m.currentCourse, m.progressMap, m.allLessons)
+	}
+}
+
+// recordCompletedCourses marks every course whose lessons are now all
+// complete, including other courses that share the lesson just finished.
+func (m *GuidedLearningModel) recordCompletedCourses() {
+	if m.database == nil {
+		return
+	}
+	for _, course := range m.courses {
+		if progress, ok := m.courseProgress[course.ID]; ok && progress.CompletedAt != nil {
+			continue
+		}
+		completed, testedOut, total := m.courseStats(course)
+		if total > 0 && completed+testedOut == total {
+			db.MarkCourseComplete(m.database, course.ID)
+		}
+	}
+}
+
+// courseStats counts the course's lessons that are completed and tested out.
+// A course is finished once every lesson is one or the other.
+func (m *GuidedLearningModel) courseStats(course types.Course) (completed, testedOut, total int) {
+	courseLessons := lessons.GetCourseLessons(course, m.progressMap, m.allLessons)
+	completed, total = lessons.GetCourseProgress(courseLessons)
+	return completed, lessons.GetCourseTestedOut(courseLessons), total
+}
+
+func (m *GuidedLearningModel) createCatalogForm() {
+	m.selectedCourseID = ""
+
+	if len(m.courses) == 0 {
+		m.catalogForm = nil
+		return
+	}
+
+	// Point out the course the learner opened most recently.
+	var lastOpened *types.CourseProgress
+	for _, course := range m.courses {
+		progress, ok := m.courseProgress[course.ID]
+		if !ok || progress.LastOpenedAt == nil {
+			continue
+		}
+		if lastOpened == nil || progress.LastOpenedAt.After(*lastOpened.LastOpenedAt) {
+			lastOpened = progress
+		}
+	}
+
+	options := make([]huh.Option[string], len(m.courses))
+	for i, course := range m.courses {
+		completed, testedOut, total := m.courseStats(course)
+
+		statusMark := " "
+		switch {
+		case total > 0 && completed+testedOut == total:
+			statusMark = "✓"
+		case lastOpened != nil && lastOpened.CourseID == course.ID:
+			statusMark = "→"
+		case completed+testedOut > 0:
+			statusMark = "•"
+		}
+
+		label := fmt.Sprintf("[%s] %s - %d/%d lessons", statusMark, course.Title, completed, total)
+		if testedOut > 0 {
+			label += fmt.Sprintf(", %d tested out", testedOut)
+		}
+		options[i] = huh.NewOption(label, course.ID)
+	}
+
+	m.catalogForm = huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[string]().
+				Title("Select a course:").
+				Options(options...).
+				Value(&m.selectedCourseID).
+				Height(15),
+		),
+	).WithWidth(90)
+}
+
+// courseTreeNode is a lesson's place in the course map: the tree drawing
+// before it and any prerequisites other than the one it is drawn under.
+type courseTreeNode struct {
+	index     int
+	prefix    string
+	alsoNeeds []string
+}
+
+// courseTree lays a course's prerequisite graph out as a tree, in course
+// order. Each lesson is drawn under its deepest prerequisite, so branches
+// the learner can choose between sit side by side, and its other
+// prerequisites are listed with it.
+func courseTree(items []types.CourseLessonItem) []courseTreeNode {
+	index := make(map[string]int, len(items))
+	for i, item := range items {
+		index[item.Lesson.ID] = i
+	}
+
+	parents := make([]int, len(items))
+	children := make([][]int, len(items))
+	var roots []int
+	for i, item := range items {
+		parents[i] = -1
+		for _, id := range item.Prerequisites {
+			j := index[id]
+			if items[j].Depth >= item.Depth {
+				continue
+			}
+			if parents[i] == -1 || items[j].Depth >= items[parents[i]].Depth {
+				parents[i] = j
+			}
+		}
+		if parents[i] == -1 {
+			roots = append(roots, i)
+		} else {
+			children[parents[i]] = append(children[parents[i]], i)
+		}
+	}
+
+	var nodes []courseTreeNode
+	var walk func(i int, prefix, indent string)
+	walk = func(i int, prefix, indent string) {
+		node := courseTreeNode{index: i, prefix: prefix}
+		for _, id := range items[i].Prerequisites {
+			if parents[i] == -1 || id != items[parents[i]].Lesson.ID {
+				node.alsoNeeds = append(node.alsoNeeds, items[index[id]].Lesson.Code)
+			}
+		}
+		nodes = append(nodes, node)
+
+		for n, child := range children[i] {
+			if n == len(children[i])-1 {
+				walk(child, indent+"└─ ", indent+"   ")
+			} else {
+				walk(child, indent+"├─ ", indent+"│  ")
+			}
+		}
+	}
+	for _, root := range roots {
+		walk(root, "", "")
 	}
-	result := string(b)
-	return result[:4] + "-" + result[4:8] + "-" + result[8:]
+	return nodes
 }
 
 func getGuidedOSShortcuts() (copy, paste string) {
@@ -707,7 +1383,7 @@ func getGuidedOSShortcuts() (copy, paste string) {
 func replaceGuidedPlaceholders(text, secretCode string) string {
 	copyShortcut, pasteShortcut := getGuidedOSShortcuts()
 
-	text = strings.ReplaceAll(text, "{SECRET_CODE}", secretCode)
+	text = strings.ReplaceAll(text, lab.SecretPlaceholder, secretCode)
 	text = strings.ReplaceAll(text, "{COPY_SHORTCUT}", copyShortcut)
 	text = strings.ReplaceAll(text, "{PASTE_SHORTCUT}", pasteShortcut)
 
@@ -718,37 +1394,34 @@ func (m *GuidedLearningModel) Open(width, height int) tea.Cmd {
 	m.width = width
 	m.height = height
 	m.isOpen = true
-	m.state = stateGuidedCourseOverview
+	m.state = stateGuidedCatalog
+	m.placement = nil
 	m.selectedLessonID = ""
+	m.currentCourse = nil
 	m.currentLesson = nil
 	m.feedback = ""
 
 	if m.database != nil {
-		progressMap, _ := db.GetAllProgress(m.database)
-		m.progressMap = progressMap
-
 		settings, _ := db.GetAllSettings(m.database)
 		m.settings = settings
 	}
 
-	courseLessons, _ := lessons.GetCourseLessons(m.progressMap, m.allLessons)
-	m.courseLessons = courseLessons
-
-	m.createForm()
-	if m.form != nil {
-		return m.form.Init()
+	m.refreshProgress()
+	m.createCatalogForm()
+	if m.catalogForm != nil {
+		return m.catalogForm.Init()
 	}
 	return nil
 }
 
 func (m *GuidedLearningModel) Close() {
-	if m.sandboxPath != "" {
-		lab.Cleanup(m.sandboxPath)
-		m.sandboxPath = ""
-	}
+	m.closeLab()
 	m.isOpen = false
-	m.state = stateGuidedCourseOverview
+	m.state = stateGuidedCatalog
+	m.placement = nil
+	m.quiz = nil
 	m.selectedLessonID = ""
+	m.currentCourse = nil
 	m.currentLesson = nil
 	m.feedback = ""
 }
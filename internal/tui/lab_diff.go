@@ -0,0 +1,134 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bobparsons/rootcamp/internal/lab"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type changeNode struct {
+	name     string
+	change   *lab.Change
+	children map[string]*changeNode
+}
+
+func newChangeNode(name string) *changeNode {
+	return &changeNode{name: name, children: map[string]*changeNode{}}
+}
+
+// renderChangeTree draws the sandbox diff as a directory tree. Directories
+// that only contain changes are shown plain so the changed entries keep
+// their place in the layout.
+func renderChangeTree(changes []lab.Change) string {
+	if len(changes) == 0 {
+		return lipgloss.NewStyle().Foreground(ColorGray).Render("No changes to the sandbox.")
+	}
+
+	root := newChangeNode(".")
+	for i := range changes {
+		node := root
+		for _, part := range strings.Split(changes[i].Path, "/") {
+			child, ok := node.children[part]
+			if !ok {
+				child = newChangeNode(part)
+				node.children[part] = child
+			}
+			node = child
+		}
+		node.change = &changes[i]
+	}
+
+	lines := []string{lipgloss.NewStyle().Foreground(ColorGray).Render("./")}
+	lines = append(lines, renderChangeChildren(root, "")...)
+	return strings.Join(lines, "\n")
+}
+
+func renderChangeChildren(node *changeNode, prefix string) []string {
+	names := make([]string, 0, len(node.children))
+	for name := range node.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var lines []string
+	for i, name := range names {
+		child := node.children[name]
+		branch, indent := "├── ", "│   "
+		if i == len(names)-1 {
+			branch, indent = "└── ", "    "
+		}
+		branchView := lipgloss.NewStyle().Foreground(ColorDarkGray).Render(prefix + branch)
+		lines = append(lines, branchView+renderChangeLabel(child))
+		lines = append(lines, renderChangeChildren(child, prefix+indent)...)
+	}
+	return lines
+}
+
+func renderChangeLabel(node *changeNode) string {
+	name := node.name
+	c := node.change
+	if (c != nil && c.IsDir) || len(node.children) > 0 {
+		name += "/"
+	}
+	if c == nil {
+		return lipgloss.NewStyle().Foreground(ColorLightGray).Render(name)
+	}
+
+	note := lipgloss.NewStyle().Foreground(ColorGray)
+	modeNote := ""
+	if c.OldMode != 0 && c.NewMode != 0 && c.OldMode.Perm() != c.NewMode.Perm() {
+		modeNote = fmt.Sprintf(" %s → %s", lab.FormatMode(c.OldMode), lab.FormatMode(c.NewMode))
+	}
+
+	switch c.Kind {
+	case lab.ChangeAdded:
+		return lipgloss.NewStyle().Foreground(ColorGreen).Render("+ "+name) + note.Render("  created")
+	case lab.ChangeRemoved:
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Render("- "+name) + note.Render("  deleted")
+	case lab.ChangeModified:
+		return lipgloss.NewStyle().Foreground(ColorOrange).Render("~ "+name) + note.Render("  modified"+modeNote)
+	case lab.ChangeMoved:
+		return lipgloss.NewStyle().Foreground(ColorBlue).Render("→ "+name) + note.Render("  moved from "+c.From+modeNote)
+	case lab.ChangeMode:
+		return lipgloss.NewStyle().Foreground(ColorPurple).Render("± "+name) + note.Render("  mode"+modeNote)
+	}
+	return name
+}
+
+// renderLabChangesView shows what the learner did to the sandbox once the lab
+// shell exits, alongside the result of checking their work.
+func renderLabChangesView(changes []lab.Change, feedback string, width, height int) string {
+	title := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(AccentBlue).
+		Padding(1, 0).
+		Render("📝 What Changed in Your Sandbox")
+
+	var feedbackView string
+	if feedback != "" {
+		feedbackView = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("196")).
+			Bold(true).
+			Width(min(width-4, 90)).
+			Render(feedback)
+	}
+
+	help := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("241")).
+		Render("Enter/ESC: Back to Lesson | [S] Resume Lab | [R] Reset Lab | [F] Restore File")
+
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		title,
+		renderChangeTree(changes),
+		"",
+		feedbackView,
+		"",
+		help,
+	)
+
+	return lipgloss.Place(width, height, lipgloss.Center, lipgloss.Center, content)
+}
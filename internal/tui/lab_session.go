@@ -0,0 +1,343 @@
+package tui
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bobparsons/rootcamp/internal/db"
+	"github.com/bobparsons/rootcamp/internal/lab"
+	"github.com/bobparsons/rootcamp/internal/terminal"
+	"github.com/bobparsons/rootcamp/internal/types"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	labCheckKey = lab.CheckKey
+	labLeaveKey = lab.LeaveKey
+)
+
+const (
+	labSideBySideMinWidth = 110
+	labPanelMinWidth      = 40
+	labPanelMaxWidth      = 70
+	labViewChrome         = 4
+)
+
+type labLayout struct {
+	sideBySide  bool
+	panelWidth  int
+	panelHeight int
+	termWidth   int
+	termHeight  int
+}
+
+// computeLabLayout splits the screen between the lesson guide and the
+// terminal. Wide screens get the guide beside the shell, narrow ones get it
+// stacked above. Sizes are for the content inside each panel's border.
+func computeLabLayout(width, height int) labLayout {
+	bodyHeight := max(height-labViewChrome, 8)
+
+	if width >= labSideBySideMinWidth {
+		panelOuter := min(max(width/3, labPanelMinWidth), labPanelMaxWidth)
+		termOuter := width - panelOuter - 1
+		return labLayout{
+			sideBySide:  true,
+			panelWidth:  panelOuter - 4,
+			panelHeight: bodyHeight - 2,
+			termWidth:   termOuter - 2,
+			termHeight:  bodyHeight - 2,
+		}
+	}
+
+	panelOuter := max(bodyHeight/3, 5)
+	termOuter := bodyHeight - panelOuter
+	return labLayout{
+		panelWidth:  max(width-4, 10),
+		panelHeight: panelOuter - 2,
+		termWidth:   max(width-2, 10),
+		termHeight:  max(termOuter-2, 3),
+	}
+}
+
+// formatLabGuide shows a multi-step lesson as a checklist, followed by the
+// instructions and hints for the step the learner is on.
+func formatLabGuide(lesson types.Lesson, step int) string {
+	var parts []string
+
+	if lesson.Instructions != "" {
+		parts = append(parts, lesson.Instructions)
+		parts = append(parts, "")
+	}
+
+	hints := lesson.Hints
+	if len(lesson.Steps) > 0 {
+		parts = append(parts, "## Steps")
+		parts = append(parts, "")
+		for i, s := range lesson.Steps {
+			mark := "○"
+			if i < step {
+				mark = "✓"
+			} else if i == step {
+				mark = "▶"
+			}
+			parts = append(parts, fmt.Sprintf("- %s %d. %s", mark, i+1, s.Title))
+		}
+		parts = append(parts, "")
+
+		if step < len(lesson.Steps) {
+			current := lesson.Steps[step]
+			parts = append(parts, fmt.Sprintf("## Step %d: %s", step+1, current.Title))
+			parts = append(parts, "")
+			if current.Instructions != "" {
+				parts = append(parts, current.Instructions)
+				parts = append(parts, "")
+			}
+			if len(current.Hints) > 0 {
+				hints = current.Hints
+			}
+		}
+	}
+
+	if len(hints) > 0 {
+		parts = append(parts, "## Hints")
+		parts = append(parts, "")
+		for _, hint := range hints {
+			parts = append(parts, "- "+hint)
+		}
+		parts = append(parts, "")
+	}
+
+	return strings.Join(parts, "\n")
+}
+
+func renderLabGuide(lesson types.Lesson, step, width int) string {
+	content := formatLabGuide(lesson, step)
+
+	renderer, err := glamour.NewTermRenderer(
+		glamour.WithAutoStyle(),
+		glamour.WithWordWrap(width),
+	)
+	if err != nil {
+		return content
+	}
+
+	rendered, err := renderer.Render(content)
+	if err != nil {
+		return content
+	}
+	return strings.TrimSpace(rendered)
+}
+
+func newLabGuide(lesson types.Lesson, step int, layout labLayout) viewport.Model {
+	guide := viewport.New(layout.panelWidth, layout.panelHeight)
+	guide.SetContent(renderLabGuide(lesson, step, layout.panelWidth))
+	return guide
+}
+
+// stepCheckDelay batches terminal output before a multi-step lesson's
+// sandbox is checked again, so steps tick off shortly after the command
+// that completes them without a check on every redraw.
+const stepCheckDelay = 400 * time.Millisecond
+
+type stepCheckMsg struct{}
+
+func scheduleStepCheck() tea.Cmd {
+	return tea.Tick(stepCheckDelay, func(time.Time) tea.Msg { return stepCheckMsg{} })
+}
+
+// saveStepProgress records every step the learner has just moved past.
+func saveStepProgress(database *sql.DB, lesson types.Lesson, from, to int) {
+	if database == nil {
+		return
+	}
+	for i := from; i < to; i++ {
+		db.MarkStepComplete(database, lesson.ID, lab.StepID(lesson, i))
+	}
+}
+
+func stepFeedback(lesson types.Lesson, done int) string {
+	if done >= len(lesson.Steps) {
+		return ""
+	}
+	return fmt.Sprintf("✅ Step %d done! Next: %s", done, lesson.Steps[done].Title)
+}
+
+// stepProgressSummary lists a multi-step lesson's steps for the detail view,
+// ticking the ones the learner has completed in any attempt.
+func stepProgressSummary(database *sql.DB, lesson types.Lesson) string {
+	if len(lesson.Steps) == 0 {
+		return ""
+	}
+
+	completed := map[string]bool{}
+	if database != nil {
+		completed, _ = db.GetCompletedSteps(database, lesson.ID)
+	}
+
+	var lines []string
+	count := 0
+	for i, step := range lesson.Steps {
+		mark := "○"
+		if completed[lab.StepID(lesson, i)] {
+			mark = "✓"
+			count++
+		}
+		lines = append(lines, fmt.Sprintf("  %s %d. %s", mark, i+1, step.Title))
+	}
+
+	header := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(AccentBlue).
+		Render(fmt.Sprintf("Steps completed: %d/%d", count, len(lesson.Steps)))
+
+	return lipgloss.JoinVertical(lipgloss.Left, header, strings.Join(lines, "\n"))
+}
+
+func startLabTerminal(lesson types.Lesson, sandboxPath string, settings *types.Settings, layout labLayout) (*terminal.Model, error) {
+	useBasicBash := false
+	isolate := false
+	if settings != nil {
+		useBasicBash = settings.UseBasicBash
+		isolate = settings.IsolateSandbox
+	}
+
+	c := lab.ShellCommand(sandboxPath, lesson, useBasicBash)
+	if isolate {
+		if err := lab.Isolate(c, sandboxPath, lesson); err != nil {
+			return nil, fmt.Errorf("%w (turn off sandbox isolation in Settings)", err)
+		}
+	}
+	return terminal.Start(c, layout.termWidth, layout.termHeight)
+}
+
+// lessonDetailHelp lists the detail view's keys. Quiz lessons have no lab to
+// start. While a lab's sandbox is still around after its shell exits, S
+// picks it back up and the reset and restore actions become available.
+func lessonDetailHelp(lesson types.Lesson, labOpen bool) string {
+	if lesson.Quiz != nil {
+		return "Arrow keys to scroll | [S] Start Quiz | ESC/Q to return"
+	}
+	if labOpen {
+		return "Arrow keys to scroll | [S] Resume Lab | [D] Changes | [R] Reset Lab | [F] Restore File | [C] Enter Code | ESC/Q to return"
+	}
+	return "Arrow keys to scroll | [S] Start Lab | [C] Enter Code | ESC/Q to return"
+}
+
+func newRestoreForm(paths []string, value *string) *huh.Form {
+	options := make([]huh.Option[string], len(paths))
+	for i, path := range paths {
+		options[i] = huh.NewOption(path, path)
+	}
+
+	return huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[string]().
+				Title("Restore a file").
+				Description("Files that are missing or changed since the lab started").
+				Options(options...).
+				Value(value).
+				Height(12),
+		),
+	).WithWidth(70).WithTheme(huh.ThemeDracula())
+}
+
+func renderRestoreView(form *huh.Form, width, height int) string {
+	if form == nil {
+		return ""
+	}
+
+	help := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("241")).
+		Render("Enter: Restore | ESC: Cancel")
+
+	modal := lipgloss.NewStyle().
+		Border(lipgloss.ThickBorder()).
+		BorderForeground(AccentBlue).
+		Padding(1, 2).
+		Render(lipgloss.JoinVertical(lipgloss.Left, form.View(), "", help))
+
+	return lipgloss.Place(width, height, lipgloss.Center, lipgloss.Center, modal)
+}
+
+func resizeLabSession(term *terminal.Model, guide *viewport.Model, lesson *types.Lesson, step, width, height int) {
+	layout := computeLabLayout(width, height)
+	if term != nil {
+		term.SetSize(layout.termWidth, layout.termHeight)
+	}
+	if guide != nil && lesson != nil {
+		guide.Width = layout.panelWidth
+		guide.Height = layout.panelHeight
+		guide.SetContent(renderLabGuide(*lesson, step, layout.panelWidth))
+	}
+}
+
+func renderLabSessionView(lesson *types.Lesson, step int, guide viewport.Model, term *terminal.Model, feedback string, width, height int) string {
+	if lesson == nil || term == nil {
+		return ""
+	}
+
+	layout := computeLabLayout(width, height)
+
+	heading := fmt.Sprintf("🧪 Lab: %s", lesson.Title)
+	if len(lesson.Steps) > 0 {
+		heading += fmt.Sprintf(" (step %d of %d)", min(step+1, len(lesson.Steps)), len(lesson.Steps))
+	}
+	title := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(AccentBlue).
+		Render(heading)
+
+	guidePanel := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(ColorPurple).
+		Padding(0, 1).
+		Width(layout.panelWidth + 2).
+		Height(layout.panelHeight).
+		Render(guide.View())
+
+	termTitle := "Terminal"
+	if offset := term.ScrollOffset(); offset > 0 {
+		termTitle = fmt.Sprintf("Terminal (scrolled back %d lines)", offset)
+	}
+
+	termPanel := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(ColorBlue).
+		Render(term.View())
+
+	var body string
+	if layout.sideBySide {
+		body = lipgloss.JoinHorizontal(lipgloss.Top, guidePanel, " ", termPanel)
+	} else {
+		body = lipgloss.JoinVertical(lipgloss.Left, guidePanel, termPanel)
+	}
+
+	feedbackView := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("196")).
+		Bold(true).
+		Render(feedback)
+
+	submitHelp := lab.CheckKeyLabel + ": Enter Answer"
+	if !lab.NeedsAnswer(lab.AtStep(*lesson, step)) {
+		submitHelp = lab.CheckKeyLabel + ": Check Work"
+	}
+
+	help := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("241")).
+		Render(termTitle + " | " + submitHelp + " | Ctrl+↑/↓: Scroll Guide | Shift+↑/↓: Scrollback | " + lab.LeaveKeyLabel + ": Leave Lab")
+
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		title,
+		"",
+		body,
+		feedbackView,
+		help,
+	)
+}
@@ -3,16 +3,13 @@ package tui
 import (
 	"database/sql"
 	"fmt"
-	"math/rand"
-	"os"
-	"os/exec"
 	"runtime"
 	"strings"
-	"time"
 
 	"github.com/bobparsons/rootcamp/internal/db"
 	"github.com/bobparsons/rootcamp/internal/lab"
 	"github.com/bobparsons/rootcamp/internal/lessons"
+	"github.com/bobparsons/rootcamp/internal/terminal"
 	"github.com/bobparsons/rootcamp/internal/types"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
@@ -25,8 +22,13 @@ import (
 const (
 	stateLessonList = iota
 	stateLessonDetail
+	stateLabSession
 	stateCodeInput
 	stateSuccess
+	stateRestoreFile
+	stateLabChanges
+	stateQuiz
+	stateQuizResults
 )
 
 type shellFinishedMsg struct{}
@@ -49,6 +51,15 @@ type LearnCommandModel struct {
 	currentLesson    *types.Lesson
 	settings         *types.Settings
 	generatedSecret  string
+	labAttemptID     int64
+	terminal         *terminal.Model
+	labGuide         viewport.Model
+	labStep          int
+	stepCheckPending bool
+	restoreForm      *huh.Form
+	restorePath      string
+	labChanges       []lab.Change
+	quiz             *quizSession
 }
 
 func NewLearnCommandModel(database *sql.DB) LearnCommandModel {
@@ -142,18 +153,6 @@ func formatLessonAbout(lesson types.Lesson) string {
 	return strings.Join(parts, "\n")
 }
 
-func generateSecretCode() string {
-	const charset = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
-	const length = 12
-	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
-	b := make([]byte, length)
-	for i := range b {
-		b[i] = charset[rng.Intn(len(charset))]
-	}
-	result := string(b)
-	return result[:4] + "-" + result[4:8] + "-" + result[8:]
-}
-
 func getOSShortcuts() (copy, paste string) {
 	if runtime.GOOS == "darwin" {
 		return "Cmd + C", "Cmd + V"
@@ -164,7 +163,7 @@ func getOSShortcuts() (copy, paste string) {
 func replacePlaceholders(text, secretCode string) string {
 	copyShortcut, pasteShortcut := getOSShortcuts()
 
-	text = strings.ReplaceAll(text, "{SECRET_CODE}", secretCode)
+	text = strings.ReplaceAll(text, lab.SecretPlaceholder, secretCode)
 	text = strings.ReplaceAll(text, "{COPY_SHORTCUT}", copyShortcut)
 	text = strings.ReplaceAll(text, "{PASTE_SHORTCUT}", pasteShortcut)
 
@@ -181,7 +180,54 @@ func (m *LearnCommandModel) Update(msg tea.Msg) (*LearnCommandModel, tea.Cmd) {
 	}
 
 	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		resizeLabSession(m.terminal, &m.labGuide, m.currentLesson, m.labStep, m.width, m.height)
+		return m, nil
+
+	case terminal.OutputMsg:
+		if m.terminal == nil {
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.terminal, cmd = m.terminal.Update(msg)
+		if m.watchesSteps() && !m.stepCheckPending {
+			m.stepCheckPending = true
+			cmd = tea.Batch(cmd, scheduleStepCheck())
+		}
+		return m, cmd
+
+	case stepCheckMsg:
+		m.stepCheckPending = false
+		if !m.watchesSteps() || m.state != stateLabSession {
+			return m, nil
+		}
+		lesson := lab.ApplySecret(*m.currentLesson, m.generatedSecret)
+		if done, _ := m.advanceSteps(lesson, ""); done {
+			return m, m.validateAnswer()
+		}
+		return m, nil
+
+	case terminal.ExitedMsg:
+		if m.terminal == nil || msg.ID != m.terminal.ID() {
+			return m, nil
+		}
+		m.closeTerminal()
+		return m, func() tea.Msg { return shellFinishedMsg{} }
+
 	case shellFinishedMsg:
+		m.labChanges = nil
+		if m.sandboxPath != "" {
+			m.labChanges, _ = lab.Diff(m.sandboxPath)
+		}
+		if m.currentLesson != nil && !lab.NeedsAnswer(lab.AtStep(*m.currentLesson, m.labStep)) {
+			m.validateAnswer()
+			if m.state != stateSuccess {
+				m.state = stateLabChanges
+			}
+			return m, nil
+		}
 		m.state = stateCodeInput
 		m.codeInput.Focus()
 		return m, nil
@@ -200,10 +246,15 @@ func (m *LearnCommandModel) Update(msg tea.Msg) (*LearnCommandModel, tea.Cmd) {
 
 		case stateCodeInput:
 			switch msg.String() {
-			case "esc", "q":
-				m.state = stateLessonDetail
+			case "esc":
 				m.codeInput.SetValue("")
 				m.feedback = ""
+				if m.terminal != nil {
+					m.codeInput.Blur()
+					m.state = stateLabSession
+					return m, nil
+				}
+				m.state = stateLessonDetail
 				return m, nil
 			case "enter":
 				return m, m.validateAnswer()
@@ -213,22 +264,95 @@ func (m *LearnCommandModel) Update(msg tea.Msg) (*LearnCommandModel, tea.Cmd) {
 				return m, cmd
 			}
 
+		case stateLabSession:
+			switch msg.String() {
+			case labCheckKey:
+				if m.currentLesson != nil && !lab.NeedsAnswer(lab.AtStep(*m.currentLesson, m.labStep)) {
+					return m, m.validateAnswer()
+				}
+				m.state = stateCodeInput
+				m.feedback = ""
+				m.codeInput.Focus()
+				return m, nil
+			case labLeaveKey:
+				m.closeLab()
+				m.state = stateLessonDetail
+				return m, nil
+			case "ctrl+up":
+				m.labGuide.LineUp(1)
+				return m, nil
+			case "ctrl+down":
+				m.labGuide.LineDown(1)
+				return m, nil
+			}
+			if m.terminal == nil {
+				return m, nil
+			}
+			var cmd tea.Cmd
+			m.terminal, cmd = m.terminal.Update(msg)
+			return m, cmd
+
+		case stateLabChanges:
+			switch msg.String() {
+			case "enter", "esc":
+				m.state = stateLessonDetail
+				return m, nil
+			case "s":
+				return m, m.resumeLab()
+			case "r":
+				return m, m.resetLab()
+			case "f":
+				return m, m.openRestore()
+			}
+			return m, nil
+
+		case stateRestoreFile:
+			if msg.String() == "esc" {
+				m.restoreForm = nil
+				m.state = stateLessonDetail
+				return m, nil
+			}
+
 		case stateLessonDetail:
 			switch msg.String() {
 			case "esc", "q":
+				m.closeLab()
 				m.state = stateLessonList
 				m.selectedLessonID = ""
 				m.currentLesson = nil
 				m.createForm()
 				return m, m.form.Init()
 			case "s":
+				if m.currentLesson != nil && m.currentLesson.Quiz != nil {
+					return m, m.startQuiz()
+				}
 				if m.currentLesson != nil && m.currentLesson.SkipSandbox {
 					m.state = stateCodeInput
 					m.codeInput.Focus()
 					return m, nil
 				}
+				if m.sandboxPath != "" {
+					return m, m.resumeLab()
+				}
 				return m, m.startLab()
+			case "r":
+				if m.sandboxPath != "" {
+					return m, m.resetLab()
+				}
+			case "f":
+				if m.sandboxPath != "" {
+					return m, m.openRestore()
+				}
+			case "d":
+				if m.sandboxPath != "" {
+					m.labChanges, _ = lab.Diff(m.sandboxPath)
+					m.state = stateLabChanges
+					return m, nil
+				}
 			case "c":
+				if m.currentLesson != nil && m.currentLesson.Quiz != nil {
+					return m, nil
+				}
 				m.state = stateCodeInput
 				m.codeInput.Focus()
 				return m, nil
@@ -238,6 +362,33 @@ func (m *LearnCommandModel) Update(msg tea.Msg) (*LearnCommandModel, tea.Cmd) {
 				return m, cmd
 			}
 
+		case stateQuiz:
+			if msg.String() == "esc" {
+				m.quiz = nil
+				m.state = stateLessonDetail
+				return m, nil
+			}
+
+		case stateQuizResults:
+			switch msg.String() {
+			case "enter", " ":
+				if m.quiz.passed {
+					m.quiz = nil
+					m.state = stateSuccess
+					return m, nil
+				}
+			case "r":
+				if !m.quiz.passed {
+					return m, m.startQuiz()
+				}
+			case "esc":
+				if !m.quiz.passed {
+					m.quiz = nil
+					m.state = stateLessonDetail
+				}
+			}
+			return m, nil
+
 		case stateLessonList:
 			if msg.String() == "esc" || msg.String() == "q" {
 				m.isOpen = false
@@ -248,6 +399,18 @@ func (m *LearnCommandModel) Update(msg tea.Msg) (*LearnCommandModel, tea.Cmd) {
 		}
 	}
 
+	if m.state == stateRestoreFile && m.restoreForm != nil {
+		return m, m.updateRestoreForm(msg)
+	}
+
+	if m.state == stateQuiz && m.quiz != nil {
+		done, cmd := m.quiz.update(msg)
+		if done {
+			m.finishQuiz()
+		}
+		return m, cmd
+	}
+
 	if m.state == stateLessonList && m.form != nil {
 		form, cmd := m.form.Update(msg)
 		if f, ok := form.(*huh.Form); ok {
@@ -284,40 +447,31 @@ func (m *LearnCommandModel) validateAnswer() tea.Cmd {
 	userInput := strings.TrimSpace(m.codeInput.Value())
 
 	lesson := *m.currentLesson
-	if lesson.SkipSandbox && m.generatedSecret != "" {
-		for i := range lesson.Requirements {
-			lesson.Requirements[i].Expected = strings.ReplaceAll(
-				lesson.Requirements[i].Expected,
-				"{SECRET_CODE}",
-				m.generatedSecret,
-			)
+	if m.generatedSecret != "" {
+		lesson = lab.ApplySecret(lesson, m.generatedSecret)
+	}
+
+	var valid bool
+	var errorMsg string
+	if len(lesson.Steps) > 0 {
+		before := m.labStep
+		valid, errorMsg = m.advanceSteps(lesson, userInput)
+		if !valid && m.labStep > before {
+			m.codeInput.SetValue("")
+			if m.terminal != nil {
+				m.codeInput.Blur()
+				m.state = stateLabSession
+			}
+			return nil
 		}
+	} else {
+		valid, errorMsg = lab.ValidateLesson(lesson, userInput, m.sandboxPath)
 	}
 
-	valid, errorMsg := lab.ValidateLesson(lesson, userInput, m.sandboxPath)
-
 	if valid {
-		db.MarkComplete(m.database, m.currentLesson.ID)
-
-		if m.sandboxPath != "" {
-			lab.Cleanup(m.sandboxPath)
-			m.sandboxPath = ""
-		}
-
-		progress, _ := db.GetProgress(m.database, m.currentLesson.ID)
-		if progress != nil {
-			m.progressMap[m.currentLesson.ID] = progress
-		}
-
-		m.state = stateSuccess
-		m.feedback = "Congratulations! You've completed this lesson! 🎉"
+		m.lessonPassed()
 	} else {
-		db.IncrementAttempts(m.database, m.currentLesson.ID)
-
-		progress, _ := db.GetProgress(m.database, m.currentLesson.ID)
-		if progress != nil {
-			m.progressMap[m.currentLesson.ID] = progress
-		}
+		m.recordFailedAttempt()
 
 		if errorMsg != "" {
 			m.feedback = fmt.Sprintf("❌ Incorrect. Hint: %s", errorMsg)
@@ -330,73 +484,217 @@ func (m *LearnCommandModel) validateAnswer() tea.Cmd {
 	return nil
 }
 
+func (m *LearnCommandModel) lessonPassed() {
+	db.MarkComplete(m.database, m.currentLesson.ID)
+
+	m.recordLabAttempt(true)
+	m.closeLab()
+
+	progress, _ := db.GetProgress(m.database, m.currentLesson.ID)
+	if progress != nil {
+		m.progressMap[m.currentLesson.ID] = progress
+	}
+
+	m.state = stateSuccess
+	m.feedback = "Congratulations! You've completed this lesson! 🎉"
+}
+
+func (m *LearnCommandModel) recordFailedAttempt() {
+	db.IncrementAttempts(m.database, m.currentLesson.ID)
+
+	progress, _ := db.GetProgress(m.database, m.currentLesson.ID)
+	if progress != nil {
+		m.progressMap[m.currentLesson.ID] = progress
+	}
+}
+
+func (m *LearnCommandModel) startQuiz() tea.Cmd {
+	quiz := prepareQuiz(*m.currentLesson.Quiz, func(text string) string {
+		return replacePlaceholders(text, m.generatedSecret)
+	})
+	m.quiz = newQuizSession(quiz)
+	m.feedback = ""
+	m.state = stateQuiz
+	return m.quiz.form.Init()
+}
+
+// finishQuiz grades the quiz and saves the answers. A passed quiz completes
+// the lesson, though the results are shown before the success screen.
+func (m *LearnCommandModel) finishQuiz() {
+	m.quiz.grade()
+	db.RecordQuizAttempt(m.database, m.currentLesson.ID, m.quiz.results, m.quiz.passed)
+
+	if m.quiz.passed {
+		m.lessonPassed()
+	} else {
+		m.recordFailedAttempt()
+	}
+	m.state = stateQuizResults
+}
+
+// watchesSteps reports whether the lab is on a step of a multi-step lesson
+// that is graded on the sandbox alone, so it can be ticked off as the
+// learner works rather than waiting for F2.
+func (m *LearnCommandModel) watchesSteps() bool {
+	if m.currentLesson == nil || m.terminal == nil || len(m.currentLesson.Steps) == 0 {
+		return false
+	}
+	return !lab.NeedsAnswer(lab.AtStep(*m.currentLesson, m.labStep))
+}
+
+// advanceSteps checks a multi-step lesson from the learner's current step,
+// saving and announcing any steps that are now complete. It reports whether
+// every step is done.
+func (m *LearnCommandModel) advanceSteps(lesson types.Lesson, userInput string) (bool, string) {
+	done, errorMsg := lab.CheckSteps(lesson, m.labStep, userInput, m.sandboxPath)
+	if done > m.labStep {
+		saveStepProgress(m.database, lesson, m.labStep, done)
+		m.labStep = done
+		m.labGuide.SetContent(renderLabGuide(*m.currentLesson, done, m.labGuide.Width))
+		m.labGuide.GotoTop()
+		m.feedback = stepFeedback(lesson, done)
+	}
+	return done == len(lesson.Steps), errorMsg
+}
+
 func (m *LearnCommandModel) startLab() tea.Cmd {
 	if m.currentLesson == nil {
 		return nil
 	}
 
-	sandboxPath, err := lab.Create(*m.currentLesson)
+	m.closeLab()
+
+	secret := lab.NewSecret()
+	lesson := lab.ApplySecret(*m.currentLesson, secret)
+	sandboxPath, err := lab.Create(lesson)
 	if err != nil {
 		m.feedback = fmt.Sprintf("Failed to create sandbox: %v", err)
 		return nil
 	}
 
+	layout := computeLabLayout(m.width, m.height)
+	term, err := startLabTerminal(lesson, sandboxPath, m.settings, layout)
+	if err != nil {
+		lab.Cleanup(sandboxPath)
+		m.feedback = fmt.Sprintf("Failed to start terminal: %v", err)
+		return nil
+	}
+
 	m.sandboxPath = sandboxPath
-	startPath := lab.GetStartPath(sandboxPath, *m.currentLesson)
-
-	useBasicBash := false
-	if m.settings != nil {
-		useBasicBash = m.settings.UseBasicBash
-	}
-
-	var instructions string
-	if useBasicBash {
-		instructions = fmt.Sprintf(`clear
-printf '\033[1;96m╔══════════════════════════════════════════════════════════════════════════════╗\n'
-printf '║\033[0m\033[1;92m                          ROOT CAMP - LAB SESSION                             \033[1;96m║\n'
-printf '╚══════════════════════════════════════════════════════════════════════════════╝\033[0m\n\n'
-printf '\033[1;33mLesson:\033[0m \033[1;97m%s\033[0m\n\n'
-cat << 'EOF'
-%s
-EOF
-printf '\n\033[1;36mYour sandbox is located at:\033[0m\n'
-printf '  \033[36m%s\033[0m\n\n'
-printf '\033[35mWhen you'"'"'re done, type \033[1;91mexit\033[0m\033[35m to return to Root Camp and enter your answer.\033[0m\n\n'
-printf '\033[1;32mGood luck!\033[0m\n\n'
-cd '%s'
-exec bash`, m.currentLesson.Title, m.currentLesson.Instructions, startPath, startPath)
+	m.generatedSecret = secret
+	m.terminal = term
+	m.labChanges = nil
+	m.labAttemptID, _ = db.StartLabAttempt(m.database, m.currentLesson.ID, secret)
+	m.labStep = 0
+	m.labGuide = newLabGuide(*m.currentLesson, m.labStep, layout)
+	m.feedback = ""
+	m.state = stateLabSession
+
+	return m.terminal.Init()
+}
+
+// resumeLab opens a new shell in the sandbox left behind by the previous
+// one, keeping the learner's work and the current attempt.
+func (m *LearnCommandModel) resumeLab() tea.Cmd {
+	if m.currentLesson == nil || m.sandboxPath == "" {
+		return nil
+	}
+
+	layout := computeLabLayout(m.width, m.height)
+	term, err := startLabTerminal(lab.ApplySecret(*m.currentLesson, m.generatedSecret), m.sandboxPath, m.settings, layout)
+	if err != nil {
+		m.feedback = fmt.Sprintf("Failed to start terminal: %v", err)
+		return nil
+	}
+
+	m.terminal = term
+	m.labGuide = newLabGuide(*m.currentLesson, m.labStep, layout)
+	m.feedback = ""
+	m.state = stateLabSession
+
+	return m.terminal.Init()
+}
+
+func (m *LearnCommandModel) resetLab() tea.Cmd {
+	if m.currentLesson == nil || m.sandboxPath == "" {
+		return nil
+	}
+
+	m.closeTerminal()
+	if err := lab.Reset(m.sandboxPath, lab.ApplySecret(*m.currentLesson, m.generatedSecret)); err != nil {
+		m.feedback = fmt.Sprintf("Failed to reset lab: %v", err)
+		return nil
+	}
+	if m.labAttemptID != 0 {
+		db.RecordLabReset(m.database, m.labAttemptID)
+	}
+	m.labStep = 0
+
+	return m.resumeLab()
+}
+
+func (m *LearnCommandModel) openRestore() tea.Cmd {
+	changed, err := lab.ChangedFiles(m.sandboxPath)
+	if err != nil {
+		m.feedback = fmt.Sprintf("Failed to check sandbox: %v", err)
+		return nil
+	}
+	if len(changed) == 0 {
+		m.feedback = "Nothing to restore: every original file is unchanged"
+		return nil
+	}
+
+	m.restorePath = ""
+	m.restoreForm = newRestoreForm(changed, &m.restorePath)
+	m.state = stateRestoreFile
+	return m.restoreForm.Init()
+}
+
+func (m *LearnCommandModel) updateRestoreForm(msg tea.Msg) tea.Cmd {
+	form, cmd := m.restoreForm.Update(msg)
+	if f, ok := form.(*huh.Form); ok {
+		m.restoreForm = f
+	}
+
+	if m.restoreForm.State != huh.StateCompleted {
+		return cmd
+	}
+
+	if err := lab.RestoreFile(m.sandboxPath, m.restorePath); err != nil {
+		m.feedback = fmt.Sprintf("Failed to restore %s: %v", m.restorePath, err)
 	} else {
-		userShell := os.Getenv("SHELL")
-		if userShell == "" {
-			userShell = "/bin/bash"
-		}
+		m.feedback = fmt.Sprintf("Restored %s. Press S to resume the lab.", m.restorePath)
+	}
+	m.restoreForm = nil
+	m.state = stateLessonDetail
+	return nil
+}
 
-		instructions = fmt.Sprintf(`clear
-printf '\033[1;96m╔══════════════════════════════════════════════════════════════════════════════╗\n'
-printf '║\033[0m\033[1;92m                          ROOT CAMP - LAB SESSION                             \033[1;96m║\n'
-printf '╚══════════════════════════════════════════════════════════════════════════════╝\033[0m\n\n'
-printf '\033[1;33mLesson:\033[0m \033[1;97m%s\033[0m\n\n'
-cat << 'EOF'
-%s
-EOF
-printf '\n\033[1;36mYour sandbox is located at:\033[0m\n'
-printf '  \033[36m%s\033[0m\n\n'
-printf '\033[35mWhen you'"'"'re done, type \033[1;91mexit\033[0m\033[35m to return to Root Camp and enter your answer.\033[0m\n\n'
-printf '\033[1;32mGood luck!\033[0m\n\n'
-cd '%s'
-exec %s`, m.currentLesson.Title, m.currentLesson.Instructions, startPath, startPath, userShell)
-	}
-
-	c := exec.Command("bash", "-c", instructions)
-	c.Dir = startPath
-	c.Stdin = os.Stdin
-	c.Stdout = os.Stdout
-	c.Stderr = os.Stderr
-	c.Env = append(os.Environ(), fmt.Sprintf("PS1=rootcamp:%s$ ", m.currentLesson.Code))
-
-	return tea.ExecProcess(c, func(err error) tea.Msg {
-		return shellFinishedMsg{}
-	})
+func (m *LearnCommandModel) closeTerminal() {
+	if m.terminal != nil {
+		m.terminal.Close()
+		m.terminal = nil
+	}
+}
+
+func (m *LearnCommandModel) recordLabAttempt(passed bool) {
+	if m.labAttemptID == 0 {
+		return
+	}
+	history, _ := lab.ReadHistory(m.sandboxPath)
+	db.FinishLabAttempt(m.database, m.labAttemptID, passed, history)
+	m.labAttemptID = 0
+}
+
+func (m *LearnCommandModel) closeLab() {
+	m.closeTerminal()
+	m.recordLabAttempt(false)
+	if m.sandboxPath != "" {
+		lab.Cleanup(m.sandboxPath)
+		m.sandboxPath = ""
+		m.generatedSecret = ""
+		m.labStep = 0
+	}
 }
 
 func (m LearnCommandModel) View() string {
@@ -407,6 +705,16 @@ func (m LearnCommandModel) View() string {
 	switch m.state {
 	case stateSuccess:
 		return m.renderSuccessView()
+	case stateQuiz:
+		return renderQuizView(m.currentLesson, m.quiz, m.width, m.height)
+	case stateQuizResults:
+		return renderQuizResultsView(m.quiz, m.width, m.height)
+	case stateRestoreFile:
+		return renderRestoreView(m.restoreForm, m.width, m.height)
+	case stateLabChanges:
+		return renderLabChangesView(m.labChanges, m.feedback, m.width, m.height)
+	case stateLabSession:
+		return renderLabSessionView(m.currentLesson, m.labStep, m.labGuide, m.terminal, m.feedback, m.width, m.height)
 	case stateCodeInput:
 		return m.renderCodeInputView()
 	case stateLessonDetail:
@@ -471,7 +779,7 @@ func (m *LearnCommandModel) renderDetailView() string {
 	instructions := lipgloss.NewStyle().
 		Foreground(lipgloss.Color("241")).
 		Width(contentWidth).
-		Render("Arrow keys to scroll | [S] Start Lab | [C] Enter Code | ESC/Q to return")
+		Render(lessonDetailHelp(*m.currentLesson, m.sandboxPath != ""))
 
 	feedbackStyle := lipgloss.NewStyle().
 		Foreground(lipgloss.Color("196")).
@@ -521,7 +829,7 @@ func (m *LearnCommandModel) renderCodeInputView() string {
 		Foreground(lipgloss.Color("241")).
 		Width(m.width).
 		Align(lipgloss.Center).
-		Render("Type your answer and press Enter to submit | ESC/Q to cancel")
+		Render("Type your answer and press Enter to submit | ESC to cancel")
 
 	feedbackStyle := lipgloss.NewStyle().
 		Foreground(lipgloss.Color("196")).
@@ -581,6 +889,14 @@ func (m *LearnCommandModel) renderSuccessView() string {
 		Align(lipgloss.Center).
 		Render("Press Enter or Space to return to lesson list")
 
+	var changesView string
+	if len(m.labChanges) > 0 {
+		changesView = lipgloss.NewStyle().
+			Width(m.width).
+			Align(lipgloss.Center).
+			Render(renderChangeTree(m.labChanges))
+	}
+
 	content := lipgloss.JoinVertical(
 		lipgloss.Left,
 		"",
@@ -590,6 +906,8 @@ func (m *LearnCommandModel) renderSuccessView() string {
 		"",
 		message,
 		"",
+		changesView,
+		"",
 		instructions,
 	)
 
@@ -614,11 +932,15 @@ func (m *LearnCommandModel) setupDetailView() {
 		rendered = "Lesson content not found"
 	}
 
-	if m.currentLesson != nil && m.currentLesson.SkipSandbox {
-		m.generatedSecret = generateSecretCode()
+	if m.currentLesson != nil && !lab.UsesSandbox(*m.currentLesson) {
+		m.generatedSecret = lab.NewSecret()
 		rendered = replacePlaceholders(rendered, m.generatedSecret)
 	}
 
+	if m.currentLesson != nil && len(m.currentLesson.Steps) > 0 {
+		rendered += "\n\n" + stepProgressSummary(m.database, *m.currentLesson)
+	}
+
 	m.viewport.SetContent(rendered)
 	m.feedback = ""
 }
@@ -635,6 +957,8 @@ func (m *LearnCommandModel) createForm() {
 		completionMark := " "
 		if progress, ok := m.progressMap[lesson.ID]; ok && progress.Completed {
 			completionMark = "✓"
+		} else if ok && progress.TestedOut {
+			completionMark = "T"
 		}
 		label := fmt.Sprintf("[%s] %-10s %s", completionMark, lesson.Code, lesson.Title)
 		options[i] = huh.NewOption(label, lesson.ID)
@@ -673,10 +997,8 @@ func (m *LearnCommandModel) Open(width, height int) tea.Cmd {
 }
 
 func (m *LearnCommandModel) Close() {
-	if m.sandboxPath != "" {
-		lab.Cleanup(m.sandboxPath)
-		m.sandboxPath = ""
-	}
+	m.closeLab()
+	m.quiz = nil
 	m.isOpen = false
 	m.state = stateLessonList
 	m.selectedLessonID = ""
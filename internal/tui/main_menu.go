@@ -34,6 +34,7 @@ func (m *MainMenuModel) createForm() {
 				Options(
 					huh.NewOption("Guided Learning", "guided_learning"),
 					huh.NewOption("Learn Command", "learn_command"),
+					huh.NewOption("Review", "review"),
 					huh.NewOption("View Progress", "view_progress"),
 					huh.NewOption("Fun Facts", "fun_facts"),
 					huh.NewOption("About Root Camp", "about"),
@@ -0,0 +1,246 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bobparsons/rootcamp/internal/db"
+	"github.com/bobparsons/rootcamp/internal/lessons"
+	"github.com/bobparsons/rootcamp/internal/types"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// placementTest tracks a placement test in progress. Modules are tested one
+// at a time from their easiest tier up, and a module's next tier is only
+// tried once the learner has passed the one before it.
+type placementTest struct {
+	modules    [][]lessons.PlacementTier
+	module     int
+	tier       int
+	challenges []types.Lesson
+	challenge  int
+	passed     []lessons.PlacementTier
+	skipped    []lessons.PlacementTier
+}
+
+func (p *placementTest) currentTier() lessons.PlacementTier {
+	return p.modules[p.module][p.tier]
+}
+
+// nextTier moves on to the module's next tier, or to the next module once
+// the last tier has been reached.
+func (p *placementTest) nextTier() {
+	p.tier++
+	if p.tier >= len(p.modules[p.module]) {
+		p.nextModule()
+	}
+}
+
+func (p *placementTest) nextModule() {
+	p.module++
+	p.tier = 0
+}
+
+func (p *placementTest) done() bool {
+	return p.module >= len(p.modules)
+}
+
+func (m *GuidedLearningModel) startPlacement() {
+	m.placement = &placementTest{modules: lessons.PlacementTiers(m.allLessons)}
+	m.state = stateGuidedPlacementIntro
+}
+
+// nextPlacementTier picks challenges from the next tier that still has
+// lessons to skip, or shows the results once every module is done.
+func (m *GuidedLearningModel) nextPlacementTier() {
+	p := m.placement
+	for ; !p.done(); p.nextTier() {
+		tier := p.currentTier()
+		if m.tierFinished(tier) {
+			continue
+		}
+		p.challenges = tier.Challenges(lessons.PlacementChallenges)
+		p.challenge = 0
+		m.showPlacementChallenge()
+		return
+	}
+	m.finishPlacement()
+}
+
+// tierFinished reports whether every lesson in the tier is already
+// completed or tested out, leaving nothing to test.
+func (m *GuidedLearningModel) tierFinished(tier lessons.PlacementTier) bool {
+	for _, lesson := range tier.Lessons {
+		progress, ok := m.progressMap[lesson.ID]
+		if !ok || (!progress.Completed && !progress.TestedOut) {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *GuidedLearningModel) showPlacementChallenge() {
+	p := m.placement
+	m.currentLesson = &p.challenges[p.challenge]
+	m.selectedLessonID = m.currentLesson.ID
+	m.state = stateGuidedLessonDetail
+	m.setupDetailView()
+}
+
+// passPlacementChallenge records a solved challenge. Solving the last one
+// of a tier tests the learner out of all of the tier's lessons.
+func (m *GuidedLearningModel) passPlacementChallenge() {
+	p := m.placement
+	p.challenge++
+	m.state = stateGuidedSuccess
+
+	if p.challenge < len(p.challenges) {
+		m.feedback = fmt.Sprintf("Challenge passed! %d more to go for %s.", len(p.challenges)-p.challenge, tierName(p.currentTier()))
+		return
+	}
+
+	tier := p.currentTier()
+	if m.database != nil {
+		db.MarkTestedOut(m.database, tier.LessonIDs())
+	}
+	p.passed = append(p.passed, tier)
+	p.challenges = nil
+	p.nextTier()
+
+	m.refreshProgress()
+	m.recordCompletedCourses()
+	m.feedback = fmt.Sprintf("You tested out of %s (%d lessons).", tierName(tier), len(tier.Lessons))
+}
+
+// continuePlacement moves on from the success screen to the next challenge.
+func (m *GuidedLearningModel) continuePlacement() {
+	p := m.placement
+	if p.challenge < len(p.challenges) {
+		m.showPlacementChallenge()
+		return
+	}
+	m.nextPlacementTier()
+}
+
+// skipPlacementModule gives up on the current tier, which also leaves the
+// module's harder tiers untested.
+func (m *GuidedLearningModel) skipPlacementModule() {
+	m.closeLab()
+	p := m.placement
+	p.skipped = append(p.skipped, p.currentTier())
+	p.challenges = nil
+	p.nextModule()
+	m.nextPlacementTier()
+}
+
+func (m *GuidedLearningModel) finishPlacement() {
+	m.closeLab()
+	m.currentLesson = nil
+	m.selectedLessonID = ""
+	m.feedback = ""
+	m.state = stateGuidedPlacementResults
+}
+
+// endPlacement leaves the results screen for the course catalog.
+func (m *GuidedLearningModel) endPlacement() tea.Cmd {
+	m.placement = nil
+	m.state = stateGuidedCatalog
+	m.refreshProgress()
+	m.createCatalogForm()
+	if m.catalogForm != nil {
+		return m.catalogForm.Init()
+	}
+	return nil
+}
+
+func tierName(tier lessons.PlacementTier) string {
+	return fmt.Sprintf("%s (%s)", formatModuleName(tier.Module), tier.Level)
+}
+
+func (m *GuidedLearningModel) placementTitle() string {
+	p := m.placement
+	return fmt.Sprintf("🧪 Placement Test: %s - challenge %d of %d", tierName(p.currentTier()), p.challenge+1, len(p.challenges))
+}
+
+func (m *GuidedLearningModel) renderPlacementIntroView() string {
+	title := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(AccentBlue).
+		Padding(1, 0).
+		Render("🧪 Placement Test")
+
+	body := lipgloss.NewStyle().
+		Width(70).
+		Render(fmt.Sprintf(
+			"Already know your way around a terminal? The placement test lets you skip ahead.\n\n"+
+				"Lessons are grouped by module and level. For each group you get up to %d of its "+
+				"lessons as challenges, worked in the lab as usual. Solve them all and every lesson "+
+				"in the group is marked as tested out, unlocking the lessons that build on them, and "+
+				"the module's next level is tried.\n\n"+
+				"Press K on a challenge to skip the rest of its module, or ESC to end the test. "+
+				"Groups you have already finished are left out.",
+			lessons.PlacementChallenges))
+
+	instructions := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("241")).
+		Render("Enter: Begin | ESC/Q: Back to Courses")
+
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		title,
+		"",
+		body,
+		"",
+		instructions,
+	)
+
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, content)
+}
+
+func (m *GuidedLearningModel) renderPlacementResultsView() string {
+	p := m.placement
+
+	title := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(ColorGreen).
+		Padding(1, 0).
+		Render("🧪 Placement Test Results")
+
+	var body strings.Builder
+	testedOut := 0
+	for _, tier := range p.passed {
+		testedOut += len(tier.Lessons)
+	}
+	fmt.Fprintf(&body, "Tested out of %d lessons.\n", testedOut)
+
+	if len(p.passed) > 0 {
+		body.WriteString("\nPassed:\n")
+		for _, tier := range p.passed {
+			fmt.Fprintf(&body, "  [T] %s - %d lessons\n", tierName(tier), len(tier.Lessons))
+		}
+	}
+	if len(p.skipped) > 0 {
+		body.WriteString("\nSkipped:\n")
+		for _, tier := range p.skipped {
+			fmt.Fprintf(&body, "  [ ] %s\n", tierName(tier))
+		}
+	}
+	if !p.done() {
+		body.WriteString("\nThe test was ended before every module was tried.\n")
+	}
+
+	instructions := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("241")).
+		Render("Enter: Back to Courses")
+
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		title,
+		"",
+		body.String(),
+		instructions,
+	)
+
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, content)
+}
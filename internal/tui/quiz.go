@@ -0,0 +1,225 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bobparsons/rootcamp/internal/lab"
+	"github.com/bobparsons/rootcamp/internal/types"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// quizSession is a quiz being taken, one form group per item. Once the form
+// is completed the answers are graded and the results kept for display.
+type quizSession struct {
+	quiz    types.Quiz
+	form    *huh.Form
+	answers []string
+	picks   [][]string
+	results []types.QuizResult
+	passed  bool
+}
+
+func newQuizSession(quiz types.Quiz) *quizSession {
+	q := &quizSession{
+		quiz:    quiz,
+		answers: make([]string, len(quiz.Items)),
+		picks:   make([][]string, len(quiz.Items)),
+	}
+
+	groups := make([]*huh.Group, len(quiz.Items))
+	for i, item := range quiz.Items {
+		title := fmt.Sprintf("%d/%d. %s", i+1, len(quiz.Items), item.Question)
+		var description string
+		if item.Command != "" {
+			description = "$ " + item.Command
+		}
+
+		var field huh.Field
+		switch {
+		case item.Type == types.QuizMultipleChoice:
+			field = huh.NewMultiSelect[string]().
+				Title(title).
+				Description(description + "\nSpace to pick every correct option, Enter when done").
+				Options(huh.NewOptions(item.Options...)...).
+				Value(&q.picks[i])
+		case len(item.Options) > 0:
+			field = huh.NewSelect[string]().
+				Title(title).
+				Description(description).
+				Options(huh.NewOptions(item.Options...)...).
+				Value(&q.answers[i])
+		default:
+			placeholder := "Type your answer..."
+			if item.Type == types.QuizComplete {
+				placeholder = "Fill in the blank..."
+			}
+			field = huh.NewInput().
+				Title(title).
+				Description(description).
+				Placeholder(placeholder).
+				Value(&q.answers[i])
+		}
+		groups[i] = huh.NewGroup(field)
+	}
+
+	q.form = huh.NewForm(groups...).WithWidth(80).WithShowHelp(true)
+	return q
+}
+
+// update passes the message to the form and reports whether the last item
+// has been answered.
+func (q *quizSession) update(msg tea.Msg) (bool, tea.Cmd) {
+	form, cmd := q.form.Update(msg)
+	if f, ok := form.(*huh.Form); ok {
+		q.form = f
+	}
+	return q.form.State == huh.StateCompleted, cmd
+}
+
+func (q *quizSession) grade() {
+	answers := make([][]string, len(q.quiz.Items))
+	for i, item := range q.quiz.Items {
+		if item.Type == types.QuizMultipleChoice {
+			answers[i] = q.picks[i]
+		} else {
+			answers[i] = []string{q.answers[i]}
+		}
+	}
+	q.results, q.passed = lab.GradeQuiz(q.quiz, answers)
+}
+
+func (q *quizSession) score() int {
+	score := 0
+	for _, result := range q.results {
+		if result.Correct {
+			score++
+		}
+	}
+	return score
+}
+
+// prepareQuiz fills in the placeholders, such as the secret code and the
+// copy and paste shortcuts, that a quiz shares with lesson text.
+func prepareQuiz(quiz types.Quiz, replace func(string) string) types.Quiz {
+	items := make([]types.QuizItem, len(quiz.Items))
+	for i, item := range quiz.Items {
+		item.Question = replace(item.Question)
+		item.Command = replace(item.Command)
+		item.Explanation = replace(item.Explanation)
+		item.Options = replaceAll(item.Options, replace)
+		item.Answers = replaceAll(item.Answers, replace)
+		items[i] = item
+	}
+	quiz.Items = items
+	return quiz
+}
+
+func replaceAll(texts []string, replace func(string) string) []string {
+	if texts == nil {
+		return nil
+	}
+	replaced := make([]string, len(texts))
+	for i, text := range texts {
+		replaced[i] = replace(text)
+	}
+	return replaced
+}
+
+func renderQuizView(lesson *types.Lesson, q *quizSession, width, height int) string {
+	if lesson == nil || q == nil {
+		return ""
+	}
+
+	title := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(AccentBlue).
+		Padding(1, 0).
+		Render(fmt.Sprintf("📝 Quiz: %s", lesson.Title))
+
+	help := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("241")).
+		Render(fmt.Sprintf("%d of %d correct to pass | ESC: Back to Lesson", lab.QuizPassScore(q.quiz), len(q.quiz.Items)))
+
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		title,
+		q.form.View(),
+		"",
+		help,
+	)
+
+	return lipgloss.Place(width, height, lipgloss.Center, lipgloss.Center, content)
+}
+
+// renderQuizResultsView marks each item right or wrong, giving the correct
+// answer and explanation for the ones that were missed.
+func renderQuizResultsView(q *quizSession, width, height int) string {
+	if q == nil {
+		return ""
+	}
+
+	heading := "❌ Quiz Failed"
+	color := lipgloss.Color("196")
+	next := "[R] Retry | ESC: Back to Lesson"
+	if q.passed {
+		heading = "✅ Quiz Passed!"
+		color = ColorGreen
+		next = "Press Enter or Space to continue"
+	}
+
+	title := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(color).
+		Padding(1, 0).
+		Render(heading)
+
+	right := lipgloss.NewStyle().Foreground(ColorGreen)
+	wrong := lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+	note := lipgloss.NewStyle().Foreground(ColorGray).PaddingLeft(4).Width(80)
+
+	var body strings.Builder
+	for i, item := range q.quiz.Items {
+		result := q.results[i]
+		if result.Correct {
+			body.WriteString(right.Render("✓ "+item.Question) + "\n")
+			continue
+		}
+
+		body.WriteString(wrong.Render("✗ "+item.Question) + "\n")
+		answer := strings.ReplaceAll(result.Answer, "\n", ", ")
+		if answer == "" {
+			answer = "(none)"
+		}
+		fmt.Fprintf(&body, "    Your answer: %s\n", answer)
+		if item.Type == types.QuizMultipleChoice {
+			fmt.Fprintf(&body, "    Correct: %s\n", strings.Join(item.Answers, ", "))
+		} else {
+			fmt.Fprintf(&body, "    Correct: %s\n", item.Answers[0])
+		}
+		if item.Explanation != "" {
+			body.WriteString(note.Render(item.Explanation) + "\n")
+		}
+	}
+
+	score := lipgloss.NewStyle().
+		Bold(true).
+		Render(fmt.Sprintf("Score: %d/%d (%d needed to pass)", q.score(), len(q.quiz.Items), lab.QuizPassScore(q.quiz)))
+
+	help := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("241")).
+		Render(next)
+
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		title,
+		body.String(),
+		score,
+		"",
+		help,
+	)
+
+	return lipgloss.Place(width, height, lipgloss.Center, lipgloss.Center, content)
+}
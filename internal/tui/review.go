@@ -0,0 +1,448 @@
+package tui
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/bobparsons/rootcamp/internal/db"
+	"github.com/bobparsons/rootcamp/internal/lab"
+	"github.com/bobparsons/rootcamp/internal/lessons"
+	"github.com/bobparsons/rootcamp/internal/terminal"
+	"github.com/bobparsons/rootcamp/internal/types"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	stateReviewIntro = iota
+	stateReviewDrill
+	stateReviewResult
+	stateReviewDone
+)
+
+// ReviewModel serves the cards that are due from completed lessons as
+// command-recall drills. Each drill is worked in a fresh copy of its
+// lesson's sandbox and graded on the commands the learner runs there.
+type ReviewModel struct {
+	database         *sql.DB
+	isOpen           bool
+	width            int
+	height           int
+	state            int
+	allLessons       []types.Lesson
+	settings         *types.Settings
+	scheduled        []lessons.ReviewItem
+	due              []lessons.ReviewItem
+	current          int
+	drill            *types.Lesson
+	sandboxPath      string
+	terminal         *terminal.Model
+	labGuide         viewport.Model
+	stepCheckPending bool
+	feedback         string
+	quality          int
+	reviewed         int
+	remembered       int
+}
+
+func NewReviewModel(database *sql.DB) ReviewModel {
+	allLessons := []types.Lesson{}
+	if data, err := lessons.LoadLessons(); err == nil {
+		allLessons = data.Lessons
+	}
+
+	return ReviewModel{
+		database:   database,
+		state:      stateReviewIntro,
+		allLessons: allLessons,
+	}
+}
+
+func (m *ReviewModel) Update(msg tea.Msg) (*ReviewModel, tea.Cmd) {
+	if !m.isOpen {
+		return m, nil
+	}
+
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		resizeLabSession(m.terminal, &m.labGuide, m.drill, 0, m.width, m.height)
+		return m, nil
+
+	case terminal.OutputMsg:
+		if m.terminal == nil {
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.terminal, cmd = m.terminal.Update(msg)
+		if !m.stepCheckPending {
+			m.stepCheckPending = true
+			cmd = tea.Batch(cmd, scheduleStepCheck())
+		}
+		return m, cmd
+
+	case stepCheckMsg:
+		m.stepCheckPending = false
+		if m.state == stateReviewDrill && m.drillPassed() {
+			m.finishDrill()
+		}
+		return m, nil
+
+	case terminal.ExitedMsg:
+		if m.terminal == nil || msg.ID != m.terminal.ID() {
+			return m, nil
+		}
+		if m.state == stateReviewDrill {
+			m.finishDrill()
+		}
+		return m, nil
+
+	case tea.KeyMsg:
+		switch m.state {
+		case stateReviewIntro:
+			switch msg.String() {
+			case "enter", " ":
+				if len(m.due) > 0 {
+					return m, m.startDrill()
+				}
+			case "esc", "q":
+				m.Close()
+			}
+			return m, nil
+
+		case stateReviewDrill:
+			switch msg.String() {
+			case labCheckKey:
+				if m.drillPassed() {
+					m.finishDrill()
+				} else {
+					m.feedback = "Not yet - run the command described in the guide"
+				}
+				return m, nil
+			case labLeaveKey:
+				m.finishDrill()
+				return m, nil
+			case "ctrl+up":
+				m.labGuide.LineUp(1)
+				return m, nil
+			case "ctrl+down":
+				m.labGuide.LineDown(1)
+				return m, nil
+			}
+			if m.terminal == nil {
+				return m, nil
+			}
+			var cmd tea.Cmd
+			m.terminal, cmd = m.terminal.Update(msg)
+			return m, cmd
+
+		case stateReviewResult:
+			switch msg.String() {
+			case "enter", " ":
+				m.current++
+				if m.current < len(m.due) {
+					return m, m.startDrill()
+				}
+				m.state = stateReviewDone
+			case "esc", "q":
+				m.state = stateReviewDone
+			}
+			return m, nil
+
+		case stateReviewDone:
+			if msg.String() == "enter" || msg.String() == " " || msg.String() == "esc" || msg.String() == "q" {
+				m.Close()
+			}
+			return m, nil
+		}
+	}
+
+	return m, nil
+}
+
+// startDrill opens the current card's drill in a new sandbox.
+func (m *ReviewModel) startDrill() tea.Cmd {
+	m.closeLab()
+
+	item := m.due[m.current]
+	drill := lab.ApplySecret(lessons.DrillLesson(item.Lesson, item.Card), lab.NewSecret())
+	sandboxPath, err := lab.Create(drill)
+	if err != nil {
+		m.feedback = fmt.Sprintf("Failed to create sandbox: %v", err)
+		return nil
+	}
+
+	layout := computeLabLayout(m.width, m.height)
+	term, err := startLabTerminal(drill, sandboxPath, m.settings, layout)
+	if err != nil {
+		lab.Cleanup(sandboxPath)
+		m.feedback = fmt.Sprintf("Failed to start terminal: %v", err)
+		return nil
+	}
+
+	m.drill = &drill
+	m.sandboxPath = sandboxPath
+	m.terminal = term
+	m.labGuide = newLabGuide(drill, 0, layout)
+	m.feedback = ""
+	m.state = stateReviewDrill
+
+	return m.terminal.Init()
+}
+
+func (m *ReviewModel) drillPassed() bool {
+	if m.drill == nil || m.sandboxPath == "" {
+		return false
+	}
+	passed, _ := lab.ValidateLesson(*m.drill, "", m.sandboxPath)
+	return passed
+}
+
+// finishDrill grades the drill from the shell history, reschedules the card
+// and shows the answer. Giving up or leaving the shell without a matching
+// command counts as forgotten.
+func (m *ReviewModel) finishDrill() {
+	item := m.due[m.current]
+
+	history, _ := lab.ReadHistory(m.sandboxPath)
+	misses, matched := 0, false
+	for _, record := range history {
+		if lab.CommandEquivalent(item.Card.Command, record.Command) {
+			matched = true
+			break
+		}
+		misses++
+	}
+	m.quality = lessons.ReviewQuality(misses, matched)
+
+	state := lessons.NextReview(item.State, m.quality, time.Now())
+	m.due[m.current].State = state
+	if m.database != nil {
+		if err := db.SaveReviewState(m.database, state); err != nil {
+			m.feedback = fmt.Sprintf("Failed to save review: %v", err)
+		}
+	}
+
+	m.reviewed++
+	if matched {
+		m.remembered++
+	}
+	m.closeLab()
+	m.state = stateReviewResult
+}
+
+func (m *ReviewModel) closeLab() {
+	if m.terminal != nil {
+		m.terminal.Close()
+		m.terminal = nil
+	}
+	if m.sandboxPath != "" {
+		lab.Cleanup(m.sandboxPath)
+		m.sandboxPath = ""
+	}
+	m.stepCheckPending = false
+}
+
+// nextDue is when the first card not in this session comes due, if any.
+func (m *ReviewModel) nextDue() (time.Time, bool) {
+	if len(m.scheduled) <= len(m.due) {
+		return time.Time{}, false
+	}
+	return m.scheduled[len(m.due)].State.DueAt, true
+}
+
+func (m ReviewModel) View() string {
+	if !m.isOpen {
+		return ""
+	}
+
+	switch m.state {
+	case stateReviewDrill:
+		return renderLabSessionView(m.drill, 0, m.labGuide, m.terminal, m.feedback, m.width, m.height)
+	case stateReviewResult:
+		return m.renderResultView()
+	case stateReviewDone:
+		return m.renderDoneView()
+	default:
+		return m.renderIntroView()
+	}
+}
+
+func (m *ReviewModel) renderIntroView() string {
+	title := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(AccentBlue).
+		Padding(1, 0).
+		Render("🔁 Review")
+
+	var text, help string
+	switch {
+	case len(m.scheduled) == 0:
+		text = "Nothing to review yet. Commands from the lessons you complete come back here for review, starting the day after."
+		help = "ESC/Q: Back to Menu"
+	case len(m.due) == 0:
+		next, _ := m.nextDue()
+		text = fmt.Sprintf("You're all caught up. The next review is due %s.", next.Format("Mon Jan 2 at 15:04"))
+		help = "ESC/Q: Back to Menu"
+	default:
+		text = fmt.Sprintf(
+			"Commands due for review: %d\n\n"+
+				"Each one is described in the guide with the command and its flags blanked out. "+
+				"Run it in the lab's shell; flags can be given in any order. The fewer tries it takes, "+
+				"the longer until you see it again.\n\n"+
+				"Press "+lab.LeaveKeyLabel+" in the lab to give up and see the answer.",
+			len(m.due))
+		help = "Enter: Begin | ESC/Q: Back to Menu"
+	}
+	if m.feedback != "" {
+		text += "\n\n" + m.feedback
+	}
+
+	body := lipgloss.NewStyle().
+		Width(70).
+		Render(text)
+
+	instructions := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("241")).
+		Render(help)
+
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		title,
+		"",
+		body,
+		"",
+		instructions,
+	)
+
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, content)
+}
+
+func (m *ReviewModel) renderResultView() string {
+	item := m.due[m.current]
+
+	heading := "✅ Remembered!"
+	color := ColorGreen
+	if m.quality < lessons.ReviewPass {
+		heading = "❌ Not this time"
+		color = lipgloss.Color("196")
+	}
+
+	title := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(color).
+		Padding(1, 0).
+		Render(heading)
+
+	body := lipgloss.NewStyle().
+		Width(70).
+		Render(fmt.Sprintf(
+			"%s\n\n    $ %s\n\nFrom %s. Next review in %s.",
+			item.Card.Prompt, item.Card.Command, item.Lesson.Title, reviewInterval(item.State.IntervalDays)))
+
+	var feedbackView string
+	if m.feedback != "" {
+		feedbackView = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("196")).
+			Bold(true).
+			Render(m.feedback)
+	}
+
+	help := "Enter: Next | ESC/Q: Stop Reviewing"
+	if m.current+1 >= len(m.due) {
+		help = "Enter: Finish"
+	}
+	instructions := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("241")).
+		Render(fmt.Sprintf("Card %d of %d | %s", m.current+1, len(m.due), help))
+
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		title,
+		body,
+		"",
+		feedbackView,
+		"",
+		instructions,
+	)
+
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, content)
+}
+
+func (m *ReviewModel) renderDoneView() string {
+	title := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(ColorGreen).
+		Padding(1, 0).
+		Render("🔁 Review Finished")
+
+	body := lipgloss.NewStyle().
+		Width(70).
+		Render(fmt.Sprintf("You remembered %d of %d commands.", m.remembered, m.reviewed))
+
+	instructions := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("241")).
+		Render("Enter: Back to Menu")
+
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		title,
+		"",
+		body,
+		"",
+		instructions,
+	)
+
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, content)
+}
+
+func reviewInterval(days int) string {
+	if days == 1 {
+		return "1 day"
+	}
+	return fmt.Sprintf("%d days", days)
+}
+
+func (m *ReviewModel) Open(width, height int) tea.Cmd {
+	m.width = width
+	m.height = height
+	m.isOpen = true
+	m.state = stateReviewIntro
+	m.current = 0
+	m.reviewed = 0
+	m.remembered = 0
+	m.feedback = ""
+
+	progressMap := make(map[string]*types.UserProgress)
+	states := make(map[string]*types.ReviewState)
+	if m.database != nil {
+		progressMap, _ = db.GetAllProgress(m.database)
+		settings, _ := db.GetAllSettings(m.database)
+		m.settings = settings
+
+		var err error
+		if states, err = db.GetAllReviewStates(m.database); err != nil {
+			m.feedback = fmt.Sprintf("Failed to load review schedule: %v", err)
+		}
+	}
+
+	m.scheduled = lessons.ScheduledReviews(m.allLessons, progressMap, states)
+	m.due = lessons.DueReviews(m.scheduled, time.Now())
+	return nil
+}
+
+func (m *ReviewModel) Close() {
+	m.closeLab()
+	m.isOpen = false
+	m.state = stateReviewIntro
+	m.drill = nil
+	m.due = nil
+	m.scheduled = nil
+}
+
+func (m ReviewModel) IsOpen() bool {
+	return m.isOpen
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 
 	"github.com/bobparsons/rootcamp/internal/db"
+	"github.com/bobparsons/rootcamp/internal/lab"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/huh"
 	"github.com/charmbracelet/lipgloss"
@@ -19,6 +20,7 @@ type SettingsModel struct {
 	allSettings []settingOption
 	// Current selected settings (bound to form)
 	selectedSettings []string
+	sandboxRoot      string
 }
 
 type settingOption struct {
@@ -28,8 +30,9 @@ type settingOption struct {
 }
 
 type settingsLoadedMsg struct {
-	options  []settingOption
-	selected []string
+	options     []settingOption
+	selected    []string
+	sandboxRoot string
 }
 
 func NewSettingsModel(database *sql.DB) SettingsModel {
@@ -41,9 +44,10 @@ func NewSettingsModel(database *sql.DB) SettingsModel {
 	}
 }
 
-func (m *SettingsModel) createForm(options []settingOption, selected []string) {
+func (m *SettingsModel) createForm(options []settingOption, selected []string, sandboxRoot string) {
 	m.selectedSettings = make([]string, len(selected))
 	copy(m.selectedSettings, selected)
+	m.sandboxRoot = sandboxRoot
 
 	huhOptions := make([]huh.Option[string], len(options))
 	for i, opt := range options {
@@ -58,6 +62,13 @@ func (m *SettingsModel) createForm(options []settingOption, selected []string) {
 				Description("Select settings to enable (Space to toggle, Enter to save)").
 				Options(huhOptions...).
 				Value(&m.selectedSettings),
+			huh.NewInput().
+				Key("sandbox_root").
+				Title("Sandbox Directory").
+				Description("Where lab sandboxes are created. Leave empty for "+lab.DefaultRoot+". Applies on next start.").
+				Placeholder(lab.DefaultRoot).
+				Validate(validateSandboxRoot).
+				Value(&m.sandboxRoot),
 		),
 	).WithWidth(70).WithTheme(huh.ThemeDracula())
 }
@@ -73,15 +84,16 @@ func (m *SettingsModel) Update(msg tea.Msg) (*SettingsModel, tea.Cmd) {
 
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		// Handle Esc or q to close without saving
-		if msg.String() == "esc" || msg.String() == "q" {
+		// Handle Esc or q to close without saving; q is just a letter while
+		// typing a path
+		if msg.String() == "esc" || (msg.String() == "q" && !m.editingText()) {
 			m.isOpen = false
 			return m, nil
 		}
 
 	case settingsLoadedMsg:
 		m.allSettings = msg.options
-		m.createForm(msg.options, msg.selected)
+		m.createForm(msg.options, msg.selected, msg.sandboxRoot)
 		return m, m.form.Init()
 	}
 
@@ -149,6 +161,11 @@ func (m *SettingsModel) Open(width, height int) tea.Cmd {
 				title:       "Use Basic Bash Shell",
 				description: "Use basic bash instead of your configured shell (for compatibility)",
 			},
+			{
+				key:         "isolate_sandbox",
+				title:       "Isolate Lab Sandboxes (Linux)",
+				description: "Run lab shells in user and mount namespaces where only the sandbox is writable",
+			},
 		}
 
 		selected := []string{}
@@ -158,9 +175,28 @@ func (m *SettingsModel) Open(width, height int) tea.Cmd {
 		if settings.UseBasicBash {
 			selected = append(selected, "use_basic_bash")
 		}
+		if settings.IsolateSandbox {
+			selected = append(selected, "isolate_sandbox")
+		}
+
+		return settingsLoadedMsg{options: options, selected: selected, sandboxRoot: settings.SandboxRoot}
+	}
+}
 
-		return settingsLoadedMsg{options: options, selected: selected}
+func (m SettingsModel) editingText() bool {
+	if m.form == nil {
+		return false
 	}
+	_, ok := m.form.GetFocusedField().(*huh.Input)
+	return ok
+}
+
+func validateSandboxRoot(value string) error {
+	if value == "" {
+		return nil
+	}
+	_, err := lab.ValidateRoot(value)
+	return err
 }
 
 func (m *SettingsModel) Close() {
@@ -186,6 +222,11 @@ func (m SettingsModel) saveSettings() tea.Cmd {
 			}
 			_ = db.SetSetting(m.database, option.key, value)
 		}
+		if m.sandboxRoot != "" {
+			// The form only checked that the directory could be created.
+			lab.CheckRoot(m.sandboxRoot)
+		}
+		_ = db.SetSetting(m.database, "sandbox_root", m.sandboxRoot)
 		return nil
 	}
 }
@@ -154,6 +154,9 @@ func (m *ViewProgressModel) renderProgressLine(label string, progStats stats.Pro
 		Render(stats.RenderProgressBar(progStats.Percentage))
 
 	percentStr := fmt.Sprintf(" %.0f%%", progStats.Percentage)
+	if progStats.TestedOut > 0 {
+		percentStr += fmt.Sprintf(" (+%d tested out)", progStats.TestedOut)
+	}
 
 	return statsLine + bar + percentStr
 }
@@ -32,6 +32,7 @@ type WelcomeModel struct {
 	settingsModel          *SettingsModel
 	guidedLearningModel *GuidedLearningModel
 	learnCommandModel      *LearnCommandModel
+	reviewModel         *ReviewModel
 	viewProgressModel   *ViewProgressModel
 	funFactsModel       *FunFactsModel
 	aboutModel          *AboutModel
@@ -63,6 +64,7 @@ func NewWelcomeModel(database *sql.DB) WelcomeModel {
 	settingsModel := NewSettingsModel(database)
 	guidedLearningModel := NewGuidedLearningModel(database)
 	learnCommandModel := NewLearnCommandModel(database)
+	reviewModel := NewReviewModel(database)
 	viewProgressModel := NewViewProgressModel(database)
 	funFactsModel := NewFunFactsModel(database)
 	aboutModel := NewAboutModel(database)
@@ -81,6 +83,7 @@ func NewWelcomeModel(database *sql.DB) WelcomeModel {
 		settingsModel:          &settingsModel,
 		guidedLearningModel: &guidedLearningModel,
 		learnCommandModel:      &learnCommandModel,
+		reviewModel:         &reviewModel,
 		viewProgressModel:   &viewProgressModel,
 		funFactsModel:       &funFactsModel,
 		aboutModel:          &aboutModel,
@@ -107,6 +110,11 @@ func tickForProvisionAnimation() tea.Cmd {
 }
 
 func (m *WelcomeModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if size, ok := msg.(tea.WindowSizeMsg); ok {
+		m.width = size.Width
+		m.height = size.Height
+	}
+
 	if m.settingsModel.IsOpen() {
 		var cmd tea.Cmd
 		m.settingsModel, cmd = m.settingsModel.Update(msg)
@@ -125,6 +133,12 @@ func (m *WelcomeModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, cmd
 	}
 
+	if m.reviewModel.IsOpen() {
+		var cmd tea.Cmd
+		m.reviewModel, cmd = m.reviewModel.Update(msg)
+		return m, cmd
+	}
+
 	if m.viewProgressModel.IsOpen() {
 		var cmd tea.Cmd
 		m.viewProgressModel, cmd = m.viewProgressModel.Update(msg)
@@ -229,6 +243,8 @@ func (m *WelcomeModel) updateMainMenu(msg tea.Msg) tea.Cmd {
 				return tea.Batch(resetCmd, m.guidedLearningModel.Open(m.width, m.height))
 			case "learn_command":
 				return tea.Batch(resetCmd, m.learnCommandModel.Open(m.width, m.height))
+			case "review":
+				return tea.Batch(resetCmd, m.reviewModel.Open(m.width, m.height))
 			case "view_progress":
 				return tea.Batch(resetCmd, m.viewProgressModel.Open(m.width, m.height))
 			case "fun_facts":
@@ -262,6 +278,9 @@ func (m WelcomeModel) View() string {
 	if m.learnCommandModel.IsOpen() {
 		return m.learnCommandModel.View()
 	}
+	if m.reviewModel.IsOpen() {
+		return m.reviewModel.View()
+	}
 	if m.viewProgressModel.IsOpen() {
 		return m.viewProgressModel.View()
 	}
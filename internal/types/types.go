@@ -7,11 +7,51 @@ type UserProgress struct {
 	Completed   bool
 	CompletedAt *time.Time
 	Attempts    int
+	TestedOut   bool
+	TestedOutAt *time.Time
+}
+
+type CourseProgress struct {
+	CourseID     string
+	StartedAt    *time.Time
+	LastOpenedAt *time.Time
+	CompletedAt  *time.Time
+}
+
+// ReviewCard is a command-recall drill drawn from a lesson's example: the
+// comment that describes a command, the command itself, and the command with
+// its program and flags blanked out.
+type ReviewCard struct {
+	ID       string
+	LessonID string
+	Prompt   string
+	Command  string
+	Cloze    string
+}
+
+// ReviewState is where a card stands in the spaced-repetition schedule.
+type ReviewState struct {
+	CardID         string
+	LessonID       string
+	Ease           float64
+	IntervalDays   int
+	Repetitions    int
+	DueAt          time.Time
+	LastReviewedAt *time.Time
+}
+
+type CommandRecord struct {
+	ExecutedAt time.Time
+	ExitStatus int
+	Dir        string
+	Command    string
 }
 
 type Settings struct {
 	SkipIntroAnimation bool
 	UseBasicBash       bool
+	IsolateSandbox     bool
+	SandboxRoot        string
 }
 
 type SettingItem struct {
@@ -35,19 +75,74 @@ type FunFactsData struct {
 }
 
 type Lesson struct {
-	ID           string         `json:"id"`
-	Command      string         `json:"command"`
-	Code         string         `json:"code"`
-	Title        string         `json:"title"`
-	Tags         []string       `json:"tags"`
-	Level        string         `json:"level"`
-	Module       string         `json:"module"`
-	About        LessonAbout    `json:"about"`
-	Hints        []string       `json:"hints"`
-	SkipSandbox  bool           `json:"skipSandbox,omitempty"`
-	Sandbox      SandboxConfig  `json:"sandbox"`
-	Instructions string         `json:"instructions"`
-	Requirements []Requirement  `json:"requirements"`
+	ID            string        `json:"id"`
+	Command       string        `json:"command"`
+	Code          string        `json:"code"`
+	Title         string        `json:"title"`
+	Tags          []string      `json:"tags"`
+	Level         string        `json:"level"`
+	Module        string        `json:"module"`
+	Prerequisites []string      `json:"prerequisites,omitempty"`
+	About         LessonAbout   `json:"about"`
+	Hints         []string      `json:"hints"`
+	SkipSandbox   bool          `json:"skipSandbox,omitempty"`
+	Sandbox       SandboxConfig `json:"sandbox"`
+	Instructions  string        `json:"instructions"`
+	Requirements  []Requirement `json:"requirements"`
+	Steps         []LessonStep  `json:"steps,omitempty"`
+	Solution      string        `json:"solution,omitempty"`
+	Quiz          *Quiz         `json:"quiz,omitempty"`
+}
+
+// LessonStep is one stage of a multi-step lesson. Steps are worked through
+// in order, each checked against its own requirements in the same way as a
+// lesson's, and the lesson is complete once the last step passes.
+type LessonStep struct {
+	ID           string        `json:"id"`
+	Title        string        `json:"title"`
+	Instructions string        `json:"instructions"`
+	Hints        []string      `json:"hints,omitempty"`
+	Requirements []Requirement `json:"requirements"`
+}
+
+// Quiz asks questions in place of a lab. A lesson with a quiz has no
+// sandbox, and is complete once at least PassScore items are answered
+// correctly, or all of them if PassScore is not set.
+type Quiz struct {
+	PassScore int        `json:"passScore,omitempty"`
+	Items     []QuizItem `json:"items"`
+}
+
+// Quiz item types. Choice items list Options and give the correct ones in
+// Answers. Output items ask what Command prints and complete items ask for
+// the part of Command marked with QuizBlank; both are typed, unless an
+// output item has Options to pick from, and Answers lists every accepted
+// answer.
+const (
+	QuizSingleChoice   = "single_choice"
+	QuizMultipleChoice = "multiple_choice"
+	QuizOutput         = "output"
+	QuizComplete       = "complete"
+
+	QuizBlank = "___"
+)
+
+type QuizItem struct {
+	ID          string   `json:"id,omitempty"`
+	Type        string   `json:"type"`
+	Question    string   `json:"question"`
+	Command     string   `json:"command,omitempty"`
+	Options     []string `json:"options,omitempty"`
+	Answers     []string `json:"answers"`
+	Explanation string   `json:"explanation,omitempty"`
+}
+
+// QuizResult is the learner's answer to one quiz item. Answers to multiple
+// choice items are joined with newlines.
+type QuizResult struct {
+	ItemID  string
+	Answer  string
+	Correct bool
 }
 
 type LessonAbout struct {
@@ -58,17 +153,83 @@ type LessonAbout struct {
 }
 
 type SandboxConfig struct {
-	StartDir string            `json:"startDir"`
-	Dirs     []string          `json:"dirs"`
-	Files    map[string]string `json:"files"`
-	Symlinks map[string]string `json:"symlinks"`
+	StartDir  string            `json:"startDir"`
+	Dirs      []string          `json:"dirs"`
+	Files     map[string]string `json:"files"`
+	Scripts   map[string]string `json:"scripts,omitempty"`
+	Symlinks  map[string]string `json:"symlinks"`
+	Modes     map[string]string `json:"modes,omitempty"`
+	Mtimes    map[string]string `json:"mtimes,omitempty"`
+	Fixtures  []SandboxFixture  `json:"fixtures,omitempty"`
+	Generated []GeneratedFile   `json:"generated,omitempty"`
+	Processes []SandboxProcess  `json:"processes,omitempty"`
+}
+
+// SandboxFixture is a generated file that cannot be written as plain text.
+// Type is "tar", "tar.gz", "zip", "gzip" or "base64". Archives hold Files,
+// Dirs and nested Fixtures as members, with Modes applied to members; gzip
+// compresses Content, or its single nested fixture; base64 decodes Content.
+type SandboxFixture struct {
+	Path     string            `json:"path"`
+	Type     string            `json:"type"`
+	Content  string            `json:"content,omitempty"`
+	Files    map[string]string `json:"files,omitempty"`
+	Dirs     []string          `json:"dirs,omitempty"`
+	Modes    map[string]string `json:"modes,omitempty"`
+	Fixtures []SandboxFixture  `json:"fixtures,omitempty"`
+}
+
+type SandboxProcess struct {
+	Name   string `json:"name"`
+	Script string `json:"script"`
 }
 
+// GeneratedFile fills a sandbox file with random data. Kind is
+// "access_log", "csv" or "words". Columns describe CSV fields as
+// "name:type[:args]". A zero Seed gives every sandbox different data, so
+// answers must be worked out with the tools rather than remembered.
+type GeneratedFile struct {
+	Path      string   `json:"path"`
+	Kind      string   `json:"kind"`
+	Lines     int      `json:"lines"`
+	Seed      uint64   `json:"seed,omitempty"`
+	Columns   []string `json:"columns,omitempty"`
+	Delimiter string   `json:"delimiter,omitempty"`
+	NoHeader  bool     `json:"noHeader,omitempty"`
+	Words     int      `json:"words,omitempty"`
+}
+
+// Requirement is a single check, or a group combining others: every member
+// of All must pass, at least one member of Any, and Not must fail. Members
+// keep their own types. A group without a type of its own counts as an
+// answer check if any of its members is one.
 type Requirement struct {
-	Type        string `json:"type"`
-	Description string `json:"description"`
-	Validator   string `json:"validator"`
-	Expected    string `json:"expected"`
+	Type        string        `json:"type"`
+	Description string        `json:"description"`
+	Validator   string        `json:"validator"`
+	Expected    string        `json:"expected"`
+	Path        string        `json:"path,omitempty"`
+	Entries     []string      `json:"entries,omitempty"`
+	Command     string        `json:"command,omitempty"`
+	Process     string        `json:"process,omitempty"`
+	Member      string        `json:"member,omitempty"`
+	Compute     *Compute      `json:"compute,omitempty"`
+	All         []Requirement `json:"all,omitempty"`
+	Any         []Requirement `json:"any,omitempty"`
+	Not         *Requirement  `json:"not,omitempty"`
+}
+
+// Compute describes how the "computed" validator works out the answer from
+// the file at the requirement's Path, as generated for the sandbox. Field
+// is 1-based like cut, split on Delimiter or a tab; Line counts from the
+// end when negative.
+type Compute struct {
+	Op        string `json:"op"`
+	Pattern   string `json:"pattern,omitempty"`
+	Field     int    `json:"field,omitempty"`
+	Delimiter string `json:"delimiter,omitempty"`
+	Line      int    `json:"line,omitempty"`
+	Header    bool   `json:"header,omitempty"`
 }
 
 type LessonsData struct {
@@ -99,10 +260,19 @@ const (
 	LessonLocked   LessonStatus = "locked"
 	LessonUnlocked LessonStatus = "unlocked"
 	LessonComplete LessonStatus = "completed"
+	// LessonTestedOut is a lesson the learner skipped by passing its tier of
+	// the placement test. It satisfies prerequisites like a completed lesson
+	// but is not counted as completed.
+	LessonTestedOut LessonStatus = "tested_out"
 )
 
+// CourseLessonItem is a lesson as it appears in a course. Prerequisites are
+// the lessons in the same course that must be completed first, and Depth is
+// the length of the longest chain of prerequisites leading to it.
 type CourseLessonItem struct {
-	Lesson   Lesson
-	Status   LessonStatus
-	Sequence int
+	Lesson        Lesson
+	Status        LessonStatus
+	Sequence      int
+	Prerequisites []string
+	Depth         int
 }